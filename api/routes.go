@@ -6,11 +6,12 @@ import (
     "bytes"
     "encoding/base64"
     "image/png"
+    "strings"
     "time"
 
     "github.com/gin-gonic/gin"
+    "github.com/pquerna/otp"
 
-    "github.com/earaujoassis/space/datastore"
     "github.com/earaujoassis/space/models"
     "github.com/earaujoassis/space/services"
     "github.com/earaujoassis/space/services/logger"
@@ -21,91 +22,828 @@ import (
     "github.com/earaujoassis/space/utils"
 )
 
+const (
+    webAuthnTimeout int = 60000 // milliseconds
+)
+
+func codeSecretImage(key *otp.Key) string {
+    var buf bytes.Buffer
+
+    img, err := key.Image(200, 200)
+    if err != nil {
+        return ""
+    }
+    png.Encode(&buf, img)
+    return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
 func ExposeRoutes(router *gin.RouterGroup) {
     users := router.Group("/users")
     {
         // Requires X-Requested-By and Origin (same-origin policy)
         users.POST("/create", requiresConformance, func(c *gin.Context) {
-            var buf bytes.Buffer
-            var imageData string
+            if !feature.Active("user.create") {
+                c.JSON(http.StatusForbidden, utils.H{
+                    "_status": "error",
+                    "_message": "User was not created",
+                    "error": "Feature is not available at this time",
+                })
+                return
+            }
+
+            user := models.User{
+                FirstName: c.PostForm("first_name"),
+                LastName: c.PostForm("last_name"),
+                Username: c.PostForm("username"),
+                Email: c.PostForm("email"),
+                Passphrase: c.PostForm("password"),
+            }
+            if !models.IsValid("essential", user) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "User was not created",
+                    "error": "Missing essential fields",
+                    "user": user,
+                })
+                return
+            }
+            if reasons := passwordRejections(user.Passphrase, user); len(reasons) > 0 {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "User was not created",
+                    "error": "Password does not conform to the policy",
+                    "reasons": reasons,
+                })
+                return
+            }
+            if user.Client.ID == 0 {
+                user.Client = services.FindOrCreateClient("Jupiter")
+            }
+            if user.Language.ID == 0 {
+                user.Language = services.FindOrCreateLanguage("English", "en-US")
+            }
+            codeSecretKey := user.GenerateCodeSecret()
+            recoverSecret, err := user.GenerateRecoverSecret()
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, utils.H{
+                    "_status": "error",
+                    "_message": "User was not created",
+                    "error": "Recover secret could not be generated",
+                })
+                return
+            }
+            imageData := codeSecretImage(codeSecretKey)
+
+            if err := services.CreateUser(&user); err != nil {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": fmt.Sprintf("%v", err),
+                    "user": user,
+                })
+            } else {
+                go logger.LogAction("user.created", utils.H{
+                    "Email": user.Email,
+                    "FirstName": user.FirstName,
+                })
+                // Users are only able to sign in once their email is confirmed
+                go logger.LogAction("user.confirmation", utils.H{
+                    "Email": user.Email,
+                    "FirstName": user.FirstName,
+                    "ConfirmationURL": confirmationURL(user),
+                })
+                c.JSON(http.StatusOK, utils.H{
+                    "_status": "created",
+                    "_message": "User was created",
+                    "recover_secret": recoverSecret,
+                    "code_secret_image": imageData,
+                    "user": user,
+                })
+            }
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        users.POST("/confirmation", requiresConformance, func(c *gin.Context) {
+            var holder string = c.PostForm("holder")
+
+            var Ip string = c.Request.RemoteAddr
+            var statusConfirmationAttempts = policy.ConfirmationAttemptStatus(Ip)
+
+            if !security.ValidEmail(holder) && !security.ValidRandomString(holder) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid holder string",
+                })
+                return
+            }
+
+            user := services.FindUserByAccountHolder(holder)
+            if user.ID != 0 && statusConfirmationAttempts != policy.Blocked {
+                statusConfirmationAttempts = policy.ConfirmationAttemptStatus(user.UUID)
+            }
+            if statusConfirmationAttempts == policy.Blocked {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "too many confirmation attempts",
+                    "attempts": statusConfirmationAttempts,
+                })
+                return
+            }
+            policy.RegisterConfirmationAttempt(Ip)
+            if user.ID != 0 && !user.Confirmed() && user.DeactivatedAt == nil {
+                policy.RegisterConfirmationAttempt(user.UUID)
+                go logger.LogAction("user.confirmation", utils.H{
+                    "Email": user.Email,
+                    "FirstName": user.FirstName,
+                    "ConfirmationURL": confirmationURL(user),
+                })
+            }
+
+            // Unknown, already confirmed and deactivated holders get this response too; it doesn't
+            // tell whether an account exists or is still pending its confirmation
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "sent",
+                "_message": "Confirmation was sent to the account email, if it is pending",
+                "attempts": statusConfirmationAttempts,
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // A user who lost their authenticator proves the ownership of the account with the recover secret
+        users.POST("/recover", requiresConformance, func(c *gin.Context) {
+            var holder string = c.PostForm("holder")
+
+            var Ip string = c.Request.RemoteAddr
+            var statusRecoveryAttempts = policy.RecoveryAttemptStatus(Ip)
+
+            if !security.ValidEmail(holder) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid email",
+                })
+                return
+            }
+
+            user := services.FindUserByAccountHolder(holder)
+            if user.ID != 0 && statusRecoveryAttempts != policy.Blocked {
+                statusRecoveryAttempts = policy.RecoveryAttemptStatus(user.UUID)
+            }
+            if user.ID == 0 || !user.Active || statusRecoveryAttempts == policy.Blocked ||
+                    !user.AuthenticRecoverSecret(c.PostForm("recover_secret")) {
+                policy.RegisterRecoveryAttempt(Ip)
+                if user.ID != 0 {
+                    policy.RegisterRecoveryAttempt(user.UUID)
+                    go logger.LogAction("user.recovery.failed", utils.H{
+                        "Email": user.Email,
+                        "FirstName": user.FirstName,
+                        "Ip": Ip,
+                    })
+                }
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": oauth.AccessDenied,
+                    "error_description": "Unauthentic user; account was not recovered",
+                    "attempts": statusRecoveryAttempts,
+                })
+                return
+            }
+
+            codeSecretKey := user.GenerateCodeSecret()
+            recoverSecret, err := user.UpdateRecoverSecret()
+            if codeSecretKey == nil || err != nil {
+                c.JSON(http.StatusInternalServerError, utils.H{
+                    "_status": "error",
+                    "_message": "Account was not recovered",
+                    "error": "Secrets could not be generated",
+                })
+                return
+            }
+            if err := services.SaveUser(&user); err != nil {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Account was not recovered",
+                    "error": fmt.Sprintf("%v", err),
+                })
+                return
+            }
+            // The lost authenticator may be in someone else's hands; every session and
+            // every enrolled TOTP device is revoked
+            services.RevokeUserAccess(user.ID)
+            services.DeleteTOTPDevicesForUser(user.ID)
+            policy.RegisterSuccessfulRecovery(user.UUID)
+            policy.RegisterSuccessfulSignIn(user.UUID)
+            go logger.LogAction("user.recovered", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "Ip": Ip,
+            })
+
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "recovered",
+                "_message": "Account was recovered",
+                "recover_secret": recoverSecret,
+                "code_secret_image": codeSecretImage(codeSecretKey),
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        users.POST("/password/forgot", requiresConformance, func(c *gin.Context) {
+            var holder string = c.PostForm("holder")
+
+            var Ip string = c.Request.RemoteAddr
+            var statusResetAttempts = policy.PasswordResetAttemptStatus(Ip)
+
+            if !security.ValidEmail(holder) && !security.ValidRandomString(holder) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid holder string",
+                })
+                return
+            }
+
+            user := services.FindUserByAccountHolder(holder)
+            if user.ID != 0 && statusResetAttempts != policy.Blocked {
+                statusResetAttempts = policy.PasswordResetAttemptStatus(user.UUID)
+            }
+            if statusResetAttempts == policy.Blocked {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "too many password reset attempts",
+                    "attempts": statusResetAttempts,
+                })
+                return
+            }
+            policy.RegisterPasswordResetAttempt(Ip)
+            if user.ID != 0 && user.Active {
+                policy.RegisterPasswordResetAttempt(user.UUID)
+                reset := services.CreatePasswordReset(user, Ip, c.Request.UserAgent())
+                if reset.UUID != "" {
+                    go logger.LogAction("user.password.forgot", utils.H{
+                        "Email": user.Email,
+                        "FirstName": user.FirstName,
+                        "Ip": Ip,
+                        "ResetURL": applicationURL("/password/reset?token=%s", reset.Token),
+                    })
+                }
+            }
+
+            // A reset is only sent to active accounts, but unknown and deactivated holders get the same
+            // response, so the form can't be used to find out which emails are registered
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "sent",
+                "_message": "Password reset was sent to the account email, if the account exists",
+                "attempts": statusResetAttempts,
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Resetting the password still requires a passcode from the authenticator
+        users.POST("/password/reset", requiresConformance, func(c *gin.Context) {
+            var token string = c.PostForm("token")
+            var newPassword string = c.PostForm("new_password")
+
+            if !security.ValidToken(token) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid token string",
+                })
+                return
+            }
+
+            reset := services.FindPasswordResetByToken(token)
+            user := services.FindUserByID(reset.UserID)
+            if reset.UUID == "" || user.ID == 0 || !user.Active {
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                    "error_description": "Invalid or expired token; password was not reset",
+                })
+                return
+            }
+
+            if reasons := passwordRejections(newPassword, user); len(reasons) > 0 {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Password was not reset",
+                    "error": "Password does not conform to the policy",
+                    "reasons": reasons,
+                })
+                return
+            }
+
+            statusSignInAttempts := policy.SignInAttemptStatus(user.UUID)
+            if statusSignInAttempts == policy.Blocked || !services.AuthenticPasscode(user, c.PostForm("passcode")) {
+                policy.RegisterSignInAttempt(user.UUID)
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                    "error_description": "Unauthentic user; password was not reset",
+                    "attempts": statusSignInAttempts,
+                })
+                return
+            }
+
+            essentialUser := user
+            essentialUser.Passphrase = newPassword
+            if !models.IsValid("essential", essentialUser) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Password was not reset",
+                    "error": "Missing essential fields",
+                })
+                return
+            }
+            if err := user.UpdatePassword(newPassword); err != nil {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Password was not reset",
+                    "error": "Password could not be updated",
+                })
+                return
+            }
+            if err := services.SaveUser(&user); err != nil {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Password was not reset",
+                    "error": fmt.Sprintf("%v", err),
+                })
+                return
+            }
+            reset.Delete()
+            services.RevokeUserAccess(user.ID)
+            policy.RegisterSuccessfulPasswordReset(user.UUID)
+            policy.RegisterSuccessfulSignIn(user.UUID)
+            go logger.LogAction("user.password.reset", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "Ip": c.Request.RemoteAddr,
+            })
+
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "reset",
+                "_message": "Password was reset",
+            })
+        })
+
+        // Authorization type: access session / Bearer (for OAuth sessions)
+        users.POST("/introspect", oAuthTokenBearerAuthorization, func(c *gin.Context) {
+            var publicId string = c.PostForm("user_id")
+
+            if !security.ValidRandomString(publicId) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid identification string",
+                })
+                return
+            }
+
+            session := c.MustGet("Session").(models.Session)
+            user := services.FindUserByPublicId(publicId)
+            if user.ID == 0 || !session.HasUser() || user.PublicId != session.User.PublicId {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            c.JSON(http.StatusOK, utils.H{
+                "user": user,
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.POST("/update", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.PostForm("user_id")
+            var email string = c.PostForm("email")
+            var newPassword string = c.PostForm("new_password")
+
+            var profileChanges []string = make([]string, 0)
+            var emailChange bool
+
+            if !security.ValidUUID(uuid) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            if value := c.PostForm("first_name"); value != "" && value != user.FirstName {
+                user.FirstName = value
+                profileChanges = append(profileChanges, "first_name")
+            }
+            if value := c.PostForm("last_name"); value != "" && value != user.LastName {
+                user.LastName = value
+                profileChanges = append(profileChanges, "last_name")
+            }
+            if value := c.PostForm("timezone_identifier"); value != "" && value != user.TimezoneIdentifier {
+                if _, err := time.LoadLocation(value); err != nil {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "error": "must use valid timezone identifier",
+                    })
+                    return
+                }
+                user.TimezoneIdentifier = value
+                profileChanges = append(profileChanges, "timezone_identifier")
+            }
+            if value := c.PostForm("language"); value != "" && value != user.Language.IsoCode {
+                language := services.FindLanguageByIsoCode(value)
+                if language.ID == 0 {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "error": "must use valid language code",
+                    })
+                    return
+                }
+                user.Language = language
+                user.LanguageID = language.ID
+                profileChanges = append(profileChanges, "language")
+            }
+            if email != "" && email != user.Email {
+                if !security.ValidEmail(email) || services.FindUserByAccountHolder(email).ID != 0 {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "error": "must use valid and available email",
+                    })
+                    return
+                }
+                emailChange = true
+            }
+            // The new password is checked before a passcode is used up
+            if newPassword != "" {
+                personalUser := user
+                if emailChange {
+                    personalUser.Email = email
+                }
+                if reasons := passwordRejections(newPassword, personalUser); len(reasons) > 0 {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "_status": "error",
+                        "_message": "User was not updated",
+                        "error": "Password does not conform to the policy",
+                        "reasons": reasons,
+                    })
+                    return
+                }
+            }
+            // Changing the password requires the current password and a passcode
+            if newPassword != "" {
+                statusSignInAttempts := policy.SignInAttemptStatus(user.UUID)
+                if statusSignInAttempts == policy.Blocked || !services.AuthenticPassword(&user, c.PostForm("current_password")) ||
+                        !services.AuthenticPasscode(user, c.PostForm("passcode")) {
+                    policy.RegisterSignInAttempt(user.UUID)
+                    c.JSON(http.StatusUnauthorized, utils.H{
+                        "error": oauth.AccessDenied,
+                        "error_description": "Unauthentic user; password was not updated",
+                        "attempts": statusSignInAttempts,
+                    })
+                    return
+                }
+                policy.RegisterSuccessfulSignIn(user.UUID)
+            }
+
+            // Essential fields are checked with the new email and the plain new password
+            essentialUser := user
+            if emailChange {
+                essentialUser.Email = email
+            }
+            if newPassword != "" {
+                essentialUser.Passphrase = newPassword
+            }
+            if !models.IsValid("essential", essentialUser) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "User was not updated",
+                    "error": "Missing essential fields",
+                })
+                return
+            }
+
+            if newPassword != "" {
+                if err := user.UpdatePassword(newPassword); err != nil {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "_status": "error",
+                        "_message": "User was not updated",
+                        "error": "Password could not be updated",
+                    })
+                    return
+                }
+            }
+            if len(profileChanges) > 0 || newPassword != "" {
+                if err := services.SaveUser(&user); err != nil {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "_status": "error",
+                        "_message": "User was not updated",
+                        "error": fmt.Sprintf("%v", err),
+                    })
+                    return
+                }
+            }
+            if len(profileChanges) > 0 {
+                go logger.LogAction("user.updated", utils.H{
+                    "Email": user.Email,
+                    "FirstName": user.FirstName,
+                    "Changes": profileChanges,
+                })
+            }
+            if newPassword != "" {
+                go logger.LogAction("user.password.changed", utils.H{
+                    "Email": user.Email,
+                    "FirstName": user.FirstName,
+                    "Ip": c.Request.RemoteAddr,
+                })
+            }
+            // The email is only changed once it is confirmed, through a link sent to the new email
+            if emailChange {
+                change := services.CreateEmailChange(user, email)
+                if change.UUID == "" {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "_status": "error",
+                        "_message": "User was not updated",
+                        "error": "Email confirmation was not created",
+                    })
+                    return
+                }
+                go logger.LogAction("user.email.confirmation", utils.H{
+                    "Email": email,
+                    "FirstName": user.FirstName,
+                    "ConfirmationURL": applicationURL("/profile/email/confirm?token=%s", change.Token),
+                })
+            }
+
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "updated",
+                "_message": "User was updated",
+                "email_confirmation_pending": emailChange,
+                "user": utils.H{
+                    "first_name": user.FirstName,
+                    "last_name": user.LastName,
+                    "email": user.Email,
+                    "timezone_identifier": user.TimezoneIdentifier,
+                    "language": user.Language.IsoCode,
+                },
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.POST("/deactivate", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.PostForm("user_id")
+
+            if !security.ValidUUID(uuid) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            // Deactivating an account requires the password and a passcode
+            statusSignInAttempts := policy.SignInAttemptStatus(user.UUID)
+            if statusSignInAttempts == policy.Blocked || !services.AuthenticPassword(&user, c.PostForm("password")) ||
+                    !services.AuthenticPasscode(user, c.PostForm("passcode")) {
+                policy.RegisterSignInAttempt(user.UUID)
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                    "error_description": "Unauthentic user; user was not deactivated",
+                    "attempts": statusSignInAttempts,
+                })
+                return
+            }
+
+            if err := services.DeactivateUser(&user); err != nil {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "User was not deactivated",
+                    "error": fmt.Sprintf("%v", err),
+                })
+                return
+            }
+            action.Delete()
+            go logger.LogAction("user.deactivated", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "GracePeriod": services.DeactivationGracePeriod(),
+            })
+
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "deactivated",
+                "_message": "User was deactivated",
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.GET("/:id/clients", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.Param("id")
+
+            if !security.ValidUUID(uuid) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            c.JSON(http.StatusOK, utils.H{
+                "clients": services.ActiveClientsForUser(user.ID),
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.DELETE("/:user_id/clients/:client_id/revoke", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var userUUID string = c.Param("user_id")
+            var clientUUID string = c.Param("client_id")
 
-            if !feature.Active("user.create") {
-                c.JSON(http.StatusForbidden, utils.H{
-                    "_status": "error",
-                    "_message": "User was not created",
-                    "error": "Feature is not available at this time",
+            if !security.ValidUUID(userUUID) || !security.ValidUUID(clientUUID) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
                 })
                 return
             }
 
-            dataStore := datastore.GetDataStoreConnection()
-            user := models.User{
-                FirstName: c.PostForm("first_name"),
-                LastName: c.PostForm("last_name"),
-                Username: c.PostForm("username"),
-                Email: c.PostForm("email"),
-                Passphrase: c.PostForm("password"),
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(userUUID)
+            if user.ID == 0 || user.ID != action.UserID {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
             }
-            if !models.IsValid("essential", user) {
+
+            client := services.FindClientByUUID(clientUUID)
+            if client.ID == 0 {
+                c.JSON(http.StatusNotFound, utils.H{
+                    "error": "Client application was not found",
+                })
+                return
+            }
+            services.RevokeClientAccess(client.ID, user.ID)
+
+            c.Status(http.StatusNoContent)
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        // WebAuthn creation options, for registering a security key
+        users.POST("/credentials/options", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.PostForm("user_id")
+
+            if !security.ValidUUID(uuid) {
                 c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            challenge, err := models.SaveWebAuthnChallenge(models.RegistrationCeremony, user.UUID)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, utils.H{
                     "_status": "error",
-                    "_message": "User was not created",
-                    "error": "Missing essential fields",
-                    "user": user,
+                    "_message": "Credential options were not created",
+                    "error": "Challenge could not be generated",
                 })
                 return
             }
-            if dataStore.NewRecord(user.Client) {
-                user.Client = services.FindOrCreateClient("Jupiter")
+            excludedCredentials := make([]utils.H, 0)
+            for _, credential := range services.CredentialsForUser(user.ID) {
+                excludedCredentials = append(excludedCredentials, utils.H{
+                    "type": "public-key",
+                    "id": credential.CredentialID,
+                })
             }
-            if dataStore.NewRecord(user.Language) {
-                user.Language = services.FindOrCreateLanguage("English", "en-US")
+            rpID, _ := relyingParty()
+
+            c.JSON(http.StatusOK, utils.H{
+                "challenge": challenge,
+                "rp": utils.H{
+                    "id": rpID,
+                    "name": "Space",
+                },
+                "user": utils.H{
+                    "id": models.EncodeCredentialData([]byte(user.UUID)),
+                    "name": user.Username,
+                    "displayName": fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+                },
+                "pubKeyCredParams": []utils.H{
+                    utils.H{"type": "public-key", "alg": security.COSEAlgorithmES256},
+                    utils.H{"type": "public-key", "alg": security.COSEAlgorithmRS256},
+                },
+                "timeout": webAuthnTimeout,
+                "attestation": "none",
+                "excludeCredentials": excludedCredentials,
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.POST("/credentials/create", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.PostForm("user_id")
+
+            if !security.ValidUUID(uuid) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
             }
-            codeSecretKey := user.GenerateCodeSecret()
-            recoverSecret := user.GenerateRecoverSecret()
-            img, err := codeSecretKey.Image(200, 200)
-            if err != nil {
-                imageData = ""
-            } else {
-                png.Encode(&buf, img)
-                imageData = base64.StdEncoding.EncodeToString(buf.Bytes())
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
             }
 
-            result := dataStore.Create(&user)
-            if count := result.RowsAffected; count < 1 {
+            clientDataJSON, clientDataErr := models.DecodeCredentialData(c.PostForm("client_data_json"))
+            attestationObject, attestationErr := models.DecodeCredentialData(c.PostForm("attestation_object"))
+            if clientDataErr != nil || attestationErr != nil {
                 c.JSON(http.StatusBadRequest, utils.H{
-                    "error": fmt.Sprintf("%v", result.GetErrors()),
-                    "user": user,
+                    "error": "must use valid credential data",
                 })
-            } else {
-                go logger.LogAction("user.created", utils.H{
-                    "Email": user.Email,
-                    "FirstName": user.FirstName,
+                return
+            }
+
+            challenge, _ := security.WebAuthnChallenge(clientDataJSON)
+            if !models.ConsumeWebAuthnChallenge(models.RegistrationCeremony, user.UUID, challenge) {
+                challenge = ""
+            }
+            rpID, origin := relyingParty()
+            webAuthnCredential, err := security.VerifyWebAuthnRegistration(attestationObject, clientDataJSON, challenge, origin, rpID)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Credential was not created",
+                    "error": fmt.Sprintf("%v", err),
                 })
-                c.JSON(http.StatusOK, utils.H{
-                    "_status": "created",
-                    "_message": "User was created",
-                    "recover_secret": recoverSecret,
-                    "code_secret_image": imageData,
-                    "user": user,
+                return
+            }
+            credential := services.CreateCredential(user, webAuthnCredential, c.PostForm("transports"), c.PostForm("name"))
+            if credential.ID == 0 {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Credential was not created",
+                    "error": "Credential is invalid or already registered",
                 })
+                return
             }
+            go logger.LogAction("user.credential.created", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "Name": credential.Name,
+                "Ip": c.ClientIP(),
+            })
+
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "created",
+                "_message": "Credential was created",
+                "credential": credential,
+            })
         })
 
-        // Authorization type: access session / Bearer (for OAuth sessions)
-        users.POST("/introspect", oAuthTokenBearerAuthorization, func(c *gin.Context) {
-            var publicId string = c.PostForm("user_id")
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.GET("/:id/credentials", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.Param("id")
 
-            if !security.ValidRandomString(publicId) {
+            if !security.ValidUUID(uuid) {
                 c.JSON(http.StatusBadRequest, utils.H{
-                    "error": "must use valid identification string",
+                    "error": "must use valid UUID for identification",
                 })
                 return
             }
 
-            session := c.MustGet("Session").(models.Session)
-            user := services.FindUserByPublicId(publicId)
-            if user.ID == 0 || user.ID != session.UserID {
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID {
                 c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
                 c.JSON(http.StatusUnauthorized, utils.H{
                     "error": oauth.AccessDenied,
@@ -114,25 +852,149 @@ func ExposeRoutes(router *gin.RouterGroup) {
             }
 
             c.JSON(http.StatusOK, utils.H{
-                "user": user,
+                "credentials": services.CredentialsForUser(user.ID),
             })
         })
 
         // Requires X-Requested-By and Origin (same-origin policy)
         // Authorization type: action token / Bearer (for web use)
-        users.POST("/update", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
-            c.String(http.StatusMethodNotAllowed, "Not implemented")
+        users.DELETE("/:user_id/credentials/:credential_id", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var userUUID string = c.Param("user_id")
+            var credentialUUID string = c.Param("credential_id")
+
+            if !security.ValidUUID(userUUID) || !security.ValidUUID(credentialUUID) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(userUUID)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            services.DeleteCredential(user.ID, credentialUUID)
+            go logger.LogAction("user.credential.deleted", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "Ip": c.ClientIP(),
+            })
+
+            c.Status(http.StatusNoContent)
         })
 
         // Requires X-Requested-By and Origin (same-origin policy)
         // Authorization type: action token / Bearer (for web use)
-        users.POST("/deactivate", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
-            c.String(http.StatusMethodNotAllowed, "Not implemented")
+        // Starts the enrollment of an additional TOTP device
+        users.POST("/devices/options", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.PostForm("user_id")
+
+            if !security.ValidUUID(uuid) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            codeSecretKey, err := models.StartTOTPEnrollment(user)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, utils.H{
+                    "_status": "error",
+                    "_message": "Device enrollment was not started",
+                    "error": "Code secret could not be generated",
+                })
+                return
+            }
+
+            c.JSON(http.StatusOK, utils.H{
+                "code_secret_image": codeSecretImage(codeSecretKey),
+            })
         })
 
         // Requires X-Requested-By and Origin (same-origin policy)
         // Authorization type: action token / Bearer (for web use)
-        users.GET("/:id/clients", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+        // Finishes the enrollment with a passcode from the new device
+        users.POST("/devices/create", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+            var uuid string = c.PostForm("user_id")
+
+            if !security.ValidUUID(uuid) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid UUID for identification",
+                })
+                return
+            }
+
+            action := c.MustGet("Action").(models.Action)
+            user := services.FindUserByUUID(uuid)
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
+                c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                })
+                return
+            }
+
+            statusSignInAttempts := policy.SignInAttemptStatus(user.UUID)
+            if statusSignInAttempts == policy.Blocked {
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                    "error_description": "Unauthentic device; device was not created",
+                    "attempts": statusSignInAttempts,
+                })
+                return
+            }
+            device, ok := models.ConfirmTOTPEnrollment(user, c.PostForm("name"), c.PostForm("passcode"))
+            if !ok {
+                policy.RegisterSignInAttempt(user.UUID)
+                c.JSON(http.StatusUnauthorized, utils.H{
+                    "error": oauth.AccessDenied,
+                    "error_description": "Unauthentic device; device was not created",
+                    "attempts": statusSignInAttempts,
+                })
+                return
+            }
+            device = services.CreateTOTPDevice(device)
+            if device.ID == 0 {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "_status": "error",
+                    "_message": "Device was not created",
+                    "error": "Device is invalid",
+                })
+                return
+            }
+            go logger.LogAction("user.device.created", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "Name": device.Name,
+                "Ip": c.ClientIP(),
+            })
+
+            c.JSON(http.StatusOK, utils.H{
+                "_status": "created",
+                "_message": "Device was created",
+                "device": device,
+            })
+        })
+
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // Authorization type: action token / Bearer (for web use)
+        users.GET("/:id/devices", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
             var uuid string = c.Param("id")
 
             if !security.ValidUUID(uuid) {
@@ -153,17 +1015,17 @@ func ExposeRoutes(router *gin.RouterGroup) {
             }
 
             c.JSON(http.StatusOK, utils.H{
-                "clients": services.ActiveClientsForUser(user.ID),
+                "devices": services.TOTPDevicesForUser(user.ID),
             })
         })
 
         // Requires X-Requested-By and Origin (same-origin policy)
         // Authorization type: action token / Bearer (for web use)
-        users.DELETE("/:user_id/clients/:client_id/revoke", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
+        users.DELETE("/:user_id/devices/:device_id", requiresConformance, actionTokenBearerAuthorization, func(c *gin.Context) {
             var userUUID string = c.Param("user_id")
-            var clientUUID string = c.Param("client_id")
+            var deviceUUID string = c.Param("device_id")
 
-            if !security.ValidUUID(userUUID) || !security.ValidUUID(clientUUID) {
+            if !security.ValidUUID(userUUID) || !security.ValidUUID(deviceUUID) {
                 c.JSON(http.StatusBadRequest, utils.H{
                     "error": "must use valid UUID for identification",
                 })
@@ -172,7 +1034,7 @@ func ExposeRoutes(router *gin.RouterGroup) {
 
             action := c.MustGet("Action").(models.Action)
             user := services.FindUserByUUID(userUUID)
-            if user.ID == 0 || user.ID != action.UserID {
+            if user.ID == 0 || user.ID != action.UserID || !services.ActionGrantsWriteAbility(action) {
                 c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
                 c.JSON(http.StatusUnauthorized, utils.H{
                     "error": oauth.AccessDenied,
@@ -180,8 +1042,12 @@ func ExposeRoutes(router *gin.RouterGroup) {
                 return
             }
 
-            client := services.FindClientByUUID(clientUUID)
-            services.RevokeClientAccess(client.ID, user.ID)
+            services.DeleteTOTPDevice(user.ID, deviceUUID)
+            go logger.LogAction("user.device.deleted", utils.H{
+                "Email": user.Email,
+                "FirstName": user.FirstName,
+                "Ip": c.ClientIP(),
+            })
 
             c.Status(http.StatusNoContent)
         })
@@ -240,7 +1106,17 @@ func ExposeRoutes(router *gin.RouterGroup) {
             if user.ID != 0 && statusSignInAttempts != policy.Blocked {
                 userID = user.UUID
                 statusSignInAttempts = policy.SignInAttemptStatus(userID)
-                if user.Authentic(c.PostForm("password"), c.PostForm("passcode")) && statusSignInAttempts != policy.Blocked {
+                authentic := statusSignInAttempts != policy.Blocked &&
+                    services.AuthenticPassword(&user, c.PostForm("password")) && authenticSecondFactor(c, user)
+                if authentic && !user.Confirmed() {
+                    c.JSON(http.StatusBadRequest, utils.H{
+                        "error": oauth.AccessDenied,
+                        "error_description": "Unconfirmed user; authorization token was not created",
+                        "confirmation_required": true,
+                    })
+                    return
+                }
+                if authentic && user.Active {
                     session := services.CreateSession(user, client,
                         c.Request.RemoteAddr,
                         c.Request.UserAgent(),
@@ -255,6 +1131,7 @@ func ExposeRoutes(router *gin.RouterGroup) {
                         })
                         policy.RegisterSuccessfulSignIn(user.UUID)
                         policy.RegisterSuccessfulSignIn(Ip)
+                        policy.RegisterSuccessfulSecurityKeyOptions(Ip)
                         c.JSON(http.StatusOK, utils.H{
                             "_status": "created",
                             "_message": "Session was created",
@@ -277,28 +1154,92 @@ func ExposeRoutes(router *gin.RouterGroup) {
             })
         })
 
+        // Requires X-Requested-By and Origin (same-origin policy)
+        // WebAuthn request options, for signing in with a security key instead of a passcode
+        sessions.POST("/options", requiresConformance, func(c *gin.Context) {
+            var holder string = c.PostForm("holder")
+            var challenge string
+
+            var Ip string = c.Request.RemoteAddr
+            var statusOptionsAttempts = policy.SecurityKeyOptionsAttemptStatus(Ip)
+
+            if !security.ValidEmail(holder) && !security.ValidRandomString(holder) {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "must use valid holder string",
+                })
+                return
+            }
+            if statusOptionsAttempts == policy.Blocked {
+                c.JSON(http.StatusBadRequest, utils.H{
+                    "error": "too many security key attempts",
+                    "attempts": statusOptionsAttempts,
+                })
+                return
+            }
+            policy.RegisterSecurityKeyOptionsAttempt(Ip)
+
+            user := services.FindUserByAccountHolder(holder)
+            allowedCredentials := make([]utils.H, 0)
+            if user.ID != 0 && user.Active {
+                credentials := services.CredentialsForUser(user.ID)
+                if len(credentials) > 0 {
+                    challenge, _ = models.SaveWebAuthnChallenge(models.AuthenticationCeremony, user.UUID)
+                }
+                for _, credential := range credentials {
+                    descriptor := utils.H{
+                        "type": "public-key",
+                        "id": credential.CredentialID,
+                    }
+                    if credential.Transports != "" {
+                        descriptor["transports"] = strings.Split(credential.Transports, ",")
+                    }
+                    allowedCredentials = append(allowedCredentials, descriptor)
+                }
+            }
+            // Unknown holders and accounts without security keys get a random challenge, which is never
+            // accepted, and no allowed credentials
+            if challenge == "" {
+                challenge, _ = security.GenerateURLSafeToken(32)
+            }
+            rpID, _ := relyingParty()
+
+            c.JSON(http.StatusOK, utils.H{
+                "challenge": challenge,
+                "rpId": rpID,
+                "timeout": webAuthnTimeout,
+                "userVerification": "discouraged",
+                "allowCredentials": allowedCredentials,
+            })
+        })
+
         // Authorization type: Basic (for OAuth clients use)
         sessions.POST("/introspect", clientBasicAuthorization, func(c *gin.Context) {
             var token string = c.PostForm("access_token")
 
-            if !security.ValidToken(token) {
+            if !security.ValidToken(token) && !security.ValidJWT(token) {
                 c.JSON(http.StatusBadRequest, utils.H{
                     "error": "must use valid token string",
                 })
                 return
             }
 
-            session := services.FindSessionByToken(token, models.AccessToken)
+            session := oauth.FindAccessSession(token)
             if session.ID == 0 {
                 c.JSON(http.StatusUnauthorized, utils.H{
                     "error": oauth.InvalidSession,
                 })
                 return
             }
+            // Sessions from the Client Credentials Grant have no user attached
+            var userId interface{}
+            if session.HasUser() {
+                userId = session.User.PublicId
+            }
             c.JSON(http.StatusOK, utils.H{
                 "active": true,
                 "scope": session.Scopes,
                 "client_id": session.Client.Key,
+                "user_id": userId,
                 "token_type": "Bearer",
             })
         })
@@ -307,14 +1248,14 @@ func ExposeRoutes(router *gin.RouterGroup) {
         sessions.POST("/invalidate", clientBasicAuthorization, func(c *gin.Context) {
             var token string = c.PostForm("access_token")
 
-            if !security.ValidToken(token) {
+            if !security.ValidToken(token) && !security.ValidJWT(token) {
                 c.JSON(http.StatusBadRequest, utils.H{
                     "error": "must use valid token string",
                 })
                 return
             }
 
-            session := services.FindSessionByToken(token, models.AccessToken)
+            session := oauth.FindAccessSession(token)
             if session.ID == 0 {
                 c.JSON(http.StatusUnauthorized, utils.H{
                     "error": oauth.InvalidSession,
@@ -7,10 +7,13 @@ import (
 
     "github.com/gin-gonic/gin"
 
+    "github.com/earaujoassis/space/config"
     "github.com/earaujoassis/space/utils"
+    "github.com/earaujoassis/space/models"
     "github.com/earaujoassis/space/oauth"
     "github.com/earaujoassis/space/services"
     "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/policy/password"
 )
 
 func scheme(request *http.Request) string {
@@ -24,6 +27,52 @@ func scheme(request *http.Request) string {
     }
 }
 
+// Links sent by email point to the configured application URL (see config.ApplicationURL)
+func applicationURL(format string, args ...interface{}) string {
+    baseURL, _ := config.ApplicationURL()
+    return baseURL + fmt.Sprintf(format, args...)
+}
+
+func confirmationURL(user models.User) string {
+    return applicationURL("/signup/confirm?token=%s", user.ConfirmationToken())
+}
+
+// WebAuthn relying party id and origin (see config.WebAuthnRelyingParty); they're checked when
+// the application starts and never taken from request headers
+func relyingParty() (string, string) {
+    rpID, origin, _ := config.WebAuthnRelyingParty()
+    return rpID, origin
+}
+
+// Passwords follow the password policy; the user's personal data is disallowed
+func passwordRejections(passphrase string, user models.User) []string {
+    return password.Validate(passphrase, user.FirstName, user.LastName, user.Username, user.Email)
+}
+
+// Sign-in accepts either a TOTP passcode or a security key (WebAuthn) assertion
+func authenticSecondFactor(c *gin.Context, user models.User) bool {
+    if credentialID := c.PostForm("credential_id"); credentialID != "" {
+        authenticatorData, err := models.DecodeCredentialData(c.PostForm("authenticator_data"))
+        if err != nil {
+            return false
+        }
+        clientDataJSON, err := models.DecodeCredentialData(c.PostForm("client_data_json"))
+        if err != nil {
+            return false
+        }
+        signature, err := models.DecodeCredentialData(c.PostForm("signature"))
+        if err != nil {
+            return false
+        }
+        rpID, origin := relyingParty()
+        return services.AuthenticSecurityKey(user, credentialID, authenticatorData, clientDataJSON, signature, origin, rpID)
+    }
+    if services.SecurityKeyRequired(user) {
+        return false
+    }
+    return services.AuthenticPasscode(user, c.PostForm("passcode"))
+}
+
 func requiresConformance(c *gin.Context) {
     host := fmt.Sprintf("%s://%s", scheme(c.Request), c.Request.Host)
     correctXRequestedBy := c.Request.Header.Get("X-Requested-By") == "SpaceApi"
@@ -93,7 +142,7 @@ func actionTokenBearerAuthorization(c *gin.Context) {
 func oAuthTokenBearerAuthorization(c *gin.Context) {
     authorizationBearer := strings.Replace(c.Request.Header.Get("Authorization"), "Bearer ", "", 1)
 
-    if !security.ValidToken(authorizationBearer) {
+    if !security.ValidToken(authorizationBearer) && !security.ValidJWT(authorizationBearer) {
         c.JSON(http.StatusBadRequest, utils.H{
             "error": "must use valid token string",
         })
@@ -102,7 +151,7 @@ func oAuthTokenBearerAuthorization(c *gin.Context) {
     }
 
     session := oauth.AccessAuthentication(authorizationBearer)
-    if session.ID == 0 || !services.SessionGrantsReadAbility(session) {
+    if session.UUID == "" || !services.SessionGrantsReadAbility(session) {
         c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\"", c.Request.RequestURI))
         c.JSON(http.StatusUnauthorized, utils.H{
             "error": oauth.AccessDenied,
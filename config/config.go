@@ -1,6 +1,9 @@
 package config
 
 import (
+    "errors"
+    "fmt"
+    "net/url"
     "os"
     "strings"
 )
@@ -25,3 +28,37 @@ func IsEnvironment(env string) bool {
 func GetConfig(key string) string {
     return os.Getenv(key)
 }
+
+// SPACE_APPLICATION_URL is the public URL of the application (e.g. `https://space.example.com`);
+// links sent by email are built from it, never from request headers
+func ApplicationURL() (string, error) {
+    value := strings.TrimRight(GetConfig("SPACE_APPLICATION_URL"), "/")
+    applicationURL, err := url.Parse(value)
+    if err != nil {
+        return "", err
+    }
+    if (applicationURL.Scheme != "http" && applicationURL.Scheme != "https") || applicationURL.Host == "" ||
+            applicationURL.RawQuery != "" || applicationURL.Fragment != "" {
+        return "", errors.New("SPACE_APPLICATION_URL must be an absolute http(s) URL")
+    }
+    return value, nil
+}
+
+// WebAuthn relying party: SPACE_WEBAUTHN_RP_ID is required and must be the host name of the
+// application URL or a registrable suffix of it; ceremonies take place at the application URL origin
+func WebAuthnRelyingParty() (string, string, error) {
+    value, err := ApplicationURL()
+    if err != nil {
+        return "", "", err
+    }
+    rpID := strings.ToLower(GetConfig("SPACE_WEBAUTHN_RP_ID"))
+    if rpID == "" {
+        return "", "", errors.New("SPACE_WEBAUTHN_RP_ID is not set")
+    }
+    applicationURL, _ := url.Parse(value)
+    host := strings.ToLower(applicationURL.Hostname())
+    if host != rpID && !strings.HasSuffix(host, "." + rpID) {
+        return "", "", errors.New("SPACE_WEBAUTHN_RP_ID must be the application host name or a suffix of it")
+    }
+    return rpID, fmt.Sprintf("%s://%s", applicationURL.Scheme, applicationURL.Host), nil
+}
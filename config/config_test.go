@@ -20,3 +20,38 @@ func TestEnvironment(t *testing.T) {
         assert.True(t, IsEnvironment(externalEnvironment), "should return true for the externalEnvironment")
     }
 }
+
+func TestApplicationURL(t *testing.T) {
+    externalApplicationURL := os.Getenv("SPACE_APPLICATION_URL")
+    defer os.Setenv("SPACE_APPLICATION_URL", externalApplicationURL)
+
+    os.Setenv("SPACE_APPLICATION_URL", "https://space.saturn.com/")
+    applicationURL, err := ApplicationURL()
+    assert.Nil(t, err, "should accept an absolute URL")
+    assert.Equal(t, "https://space.saturn.com", applicationURL, "should drop the trailing slash")
+    for _, invalid := range []string{"", "space.saturn.com", "ftp://space.saturn.com", "https://space.saturn.com/?next=1"} {
+        os.Setenv("SPACE_APPLICATION_URL", invalid)
+        _, err = ApplicationURL()
+        assert.NotNil(t, err, "should refuse an invalid URL")
+    }
+}
+
+func TestWebAuthnRelyingParty(t *testing.T) {
+    externalApplicationURL := os.Getenv("SPACE_APPLICATION_URL")
+    externalRPID := os.Getenv("SPACE_WEBAUTHN_RP_ID")
+    defer os.Setenv("SPACE_APPLICATION_URL", externalApplicationURL)
+    defer os.Setenv("SPACE_WEBAUTHN_RP_ID", externalRPID)
+
+    os.Setenv("SPACE_APPLICATION_URL", "https://space.saturn.com:8443/")
+    os.Setenv("SPACE_WEBAUTHN_RP_ID", "saturn.com")
+    rpID, origin, err := WebAuthnRelyingParty()
+    assert.Nil(t, err, "should accept a suffix of the host name")
+    assert.Equal(t, "saturn.com", rpID, "should use the configured id")
+    assert.Equal(t, "https://space.saturn.com:8443", origin, "should use the application URL origin")
+    os.Setenv("SPACE_WEBAUTHN_RP_ID", "")
+    _, _, err = WebAuthnRelyingParty()
+    assert.NotNil(t, err, "should require the id")
+    os.Setenv("SPACE_WEBAUTHN_RP_ID", "evilsaturn.com")
+    _, _, err = WebAuthnRelyingParty()
+    assert.NotNil(t, err, "should refuse an id which isn't a suffix of the host name")
+}
@@ -0,0 +1,175 @@
+package datastore
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/jinzhu/gorm"
+)
+
+// Migrations are applied in the order of their versions, which must be unique and increasing.
+// A migration without a Down function is irreversible and stops rollbacks
+type Migration struct {
+    Version int64
+    Name string
+    Up func(db *gorm.DB) error
+    Down func(db *gorm.DB) error
+}
+
+// Applied migrations are recorded in the `schema_migrations` table
+type SchemaMigration struct {
+    Version int64               `gorm:"primary_key;type:bigint"`
+    AppliedAt time.Time         `gorm:"not null"`
+}
+
+type MigrationStatus struct {
+    Migration
+    // It's nil while the migration is pending
+    AppliedAt *time.Time
+}
+
+// Migrate applies the pending migrations, returning the ones which were applied
+func Migrate() ([]Migration, error) {
+    return migrate(GetDataStoreConnection(), schemaMigrations)
+}
+
+// Rollback reverts the latest `steps` applied migrations, returning the ones which were reverted
+func Rollback(steps int) ([]Migration, error) {
+    return rollback(GetDataStoreConnection(), schemaMigrations, steps)
+}
+
+func Status() ([]MigrationStatus, error) {
+    return status(GetDataStoreConnection(), schemaMigrations)
+}
+
+func checkMigrations(migrations []Migration) error {
+    var previous int64
+    for _, migration := range migrations {
+        if migration.Version <= previous {
+            return fmt.Errorf("Migration %d is out of order", migration.Version)
+        }
+        if migration.Up == nil {
+            return fmt.Errorf("Migration %d has no Up function", migration.Version)
+        }
+        previous = migration.Version
+    }
+    return nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int64]time.Time, error) {
+    var records []SchemaMigration
+
+    if !db.HasTable(&SchemaMigration{}) {
+        if err := db.CreateTable(&SchemaMigration{}).Error; err != nil {
+            return nil, err
+        }
+    }
+    if err := db.Find(&records).Error; err != nil {
+        return nil, err
+    }
+    applied := make(map[int64]time.Time)
+    for _, record := range records {
+        applied[record.Version] = record.AppliedAt
+    }
+    return applied, nil
+}
+
+func status(db *gorm.DB, migrations []Migration) ([]MigrationStatus, error) {
+    var states []MigrationStatus
+
+    if err := checkMigrations(migrations); err != nil {
+        return nil, err
+    }
+    applied, err := appliedMigrations(db)
+    if err != nil {
+        return nil, err
+    }
+    for _, migration := range migrations {
+        state := MigrationStatus{Migration: migration}
+        if appliedAt, ok := applied[migration.Version]; ok {
+            state.AppliedAt = &appliedAt
+        }
+        states = append(states, state)
+    }
+    return states, nil
+}
+
+func pendingMigrations(db *gorm.DB, migrations []Migration) ([]Migration, error) {
+    var pending []Migration
+
+    states, err := status(db, migrations)
+    if err != nil {
+        return nil, err
+    }
+    for _, state := range states {
+        if state.AppliedAt == nil {
+            pending = append(pending, state.Migration)
+        }
+    }
+    return pending, nil
+}
+
+// Migrations are not wrapped in transactions: MySQL commits schema changes implicitly and GORM
+// inspects tables outside of them. A failing migration stops the run and isn't recorded
+func migrate(db *gorm.DB, migrations []Migration) ([]Migration, error) {
+    var applied []Migration
+
+    pending, err := pendingMigrations(db, migrations)
+    if err != nil {
+        return nil, err
+    }
+    for _, migration := range pending {
+        if err := migration.Up(db); err != nil {
+            return applied, fmt.Errorf("Migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+        }
+        record := SchemaMigration{Version: migration.Version, AppliedAt: time.Now().UTC()}
+        if err := db.Create(&record).Error; err != nil {
+            return applied, err
+        }
+        applied = append(applied, migration)
+    }
+    return applied, nil
+}
+
+func rollback(db *gorm.DB, migrations []Migration, steps int) ([]Migration, error) {
+    var reverted []Migration
+    var versions []int64
+
+    if steps < 1 {
+        return nil, errors.New("At least one migration must be rolled back")
+    }
+    if err := checkMigrations(migrations); err != nil {
+        return nil, err
+    }
+    applied, err := appliedMigrations(db)
+    if err != nil {
+        return nil, err
+    }
+    for version := range applied {
+        versions = append(versions, version)
+    }
+    sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+    known := make(map[int64]Migration)
+    for _, migration := range migrations {
+        known[migration.Version] = migration
+    }
+    for i := 0; i < steps && i < len(versions); i++ {
+        migration, ok := known[versions[i]]
+        if !ok {
+            return reverted, fmt.Errorf("Migration %d is unknown", versions[i])
+        }
+        if migration.Down == nil {
+            return reverted, fmt.Errorf("Migration %d (%s) is irreversible", migration.Version, migration.Name)
+        }
+        if err := migration.Down(db); err != nil {
+            return reverted, fmt.Errorf("Migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+        }
+        if err := db.Where("version = ?", migration.Version).Delete(SchemaMigration{}).Error; err != nil {
+            return reverted, err
+        }
+        reverted = append(reverted, migration)
+    }
+    return reverted, nil
+}
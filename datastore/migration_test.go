@@ -0,0 +1,162 @@
+package datastore
+
+import (
+    "errors"
+    "os"
+    "testing"
+    "time"
+
+    "github.com/jinzhu/gorm"
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/security"
+)
+
+func testingDataStore(t *testing.T) *gorm.DB {
+    db, err := gorm.Open(SQLiteDriver, ":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Every connection would open a distinct in-memory database
+    db.DB().SetMaxOpenConns(1)
+    return db
+}
+
+func testingMigrations() []Migration {
+    return []Migration{
+        {
+            Version: 1,
+            Name: "create_planets",
+            Up: func(db *gorm.DB) error {
+                return db.Exec("CREATE TABLE planets (name varchar(255) NOT NULL)").Error
+            },
+            Down: func(db *gorm.DB) error {
+                return db.Exec("DROP TABLE planets").Error
+            },
+        },
+        {
+            Version: 2,
+            Name: "insert_planets",
+            Up: func(db *gorm.DB) error {
+                return db.Exec("INSERT INTO planets (name) VALUES ('Saturn'), ('Jupiter')").Error
+            },
+            Down: func(db *gorm.DB) error {
+                return db.Exec("DELETE FROM planets").Error
+            },
+        },
+    }
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+    var count int
+
+    db := testingDataStore(t)
+    migrations := testingMigrations()
+
+    applied, err := migrate(db, migrations)
+    assert.Nil(t, err, "should apply the migrations")
+    assert.Equal(t, 2, len(applied), "should apply every pending migration")
+    db.Table("planets").Count(&count)
+    assert.Equal(t, 2, count, "should run the data migration")
+    applied, err = migrate(db, migrations)
+    assert.Nil(t, err, "should not fail without pending migrations")
+    assert.Equal(t, 0, len(applied), "should not apply a migration twice")
+
+    states, err := status(db, migrations)
+    assert.Nil(t, err, "should list the migrations")
+    assert.NotNil(t, states[0].AppliedAt, "should record the applied migrations")
+    assert.NotNil(t, states[1].AppliedAt, "should record the applied migrations")
+
+    reverted, err := rollback(db, migrations, 1)
+    assert.Nil(t, err, "should roll back a migration")
+    assert.Equal(t, int64(2), reverted[0].Version, "should roll back the latest migration")
+    db.Table("planets").Count(&count)
+    assert.Equal(t, 0, count, "should run the Down function")
+    states, _ = status(db, migrations)
+    assert.NotNil(t, states[0].AppliedAt, "should keep the earlier migrations")
+    assert.Nil(t, states[1].AppliedAt, "should mark the reverted migration as pending")
+
+    reverted, err = rollback(db, migrations, 5)
+    assert.Nil(t, err, "should stop rolling back once every migration is reverted")
+    assert.Equal(t, 1, len(reverted), "should roll back the applied migrations only")
+    assert.False(t, db.HasTable("planets"), "should have reverted the schema")
+}
+
+func TestMigrateFailures(t *testing.T) {
+    db := testingDataStore(t)
+    migrations := testingMigrations()
+    migrations[1].Up = func(db *gorm.DB) error { return errors.New("Failing migration") }
+
+    applied, err := migrate(db, migrations)
+    assert.NotNil(t, err, "should report a failing migration")
+    assert.Equal(t, 1, len(applied), "should apply the migrations before the failing one")
+    pending, _ := pendingMigrations(db, migrations)
+    assert.Equal(t, int64(2), pending[0].Version, "should not record the failing migration")
+
+    migrations[0].Down = nil
+    _, err = rollback(db, migrations, 1)
+    assert.NotNil(t, err, "should refuse to roll back an irreversible migration")
+    assert.True(t, db.HasTable("planets"), "should not revert an irreversible migration")
+    _, err = rollback(db, migrations, 0)
+    assert.NotNil(t, err, "should require at least one step")
+
+    outOfOrder := []Migration{testingMigrations()[1], testingMigrations()[0]}
+    _, err = migrate(db, outOfOrder)
+    assert.NotNil(t, err, "should refuse migrations out of order")
+}
+
+func TestSchemaMigrations(t *testing.T) {
+    var token, secret string
+    var count int
+
+    os.Setenv("SPACE_STORAGE_SECRET", "Mx2kvQ9sT4bN7pLw3eRz8yUc5aHf6jDg")
+    db := testingDataStore(t)
+
+    _, err := migrate(db, schemaMigrations[:1])
+    assert.Nil(t, err, "should create the initial schema")
+    assert.True(t, db.HasTable(&models.Session{}), "should create the tables")
+    err = db.Exec("INSERT INTO sessions (uuid, user_id, client_id, moment, ip, user_agent, token, token_type, scopes, " +
+        "created_at, updated_at) VALUES ('uuid', 1, 1, 0, '127.0.0.1', 'Testing', 'plaintext', 'access_token', 'read', ?, ?)",
+        time.Now(), time.Now()).Error
+    assert.Nil(t, err, "should insert a plaintext token")
+    pending, _ := pendingMigrations(db, schemaMigrations)
+    assert.Equal(t, len(schemaMigrations) - 1, len(pending), "should keep the other migrations pending")
+
+    legacySecret, _ := security.Encrypt([]byte(os.Getenv("SPACE_STORAGE_SECRET")), []byte("code secret"))
+    err = db.Exec("INSERT INTO totp_devices (uuid, user_id, name, code_secret, created_at, updated_at) " +
+        "VALUES ('uuid', 1, 'Phone', ?, ?, ?)", legacySecret, time.Now(), time.Now()).Error
+    assert.Nil(t, err, "should insert a legacy secret")
+
+    _, err = migrate(db, schemaMigrations)
+    assert.Nil(t, err, "should apply the data migrations")
+    db.Table("sessions").Select("token").Row().Scan(&token)
+    assert.Equal(t, models.DigestToken("plaintext"), token, "should digest the plaintext tokens")
+    db.Table("totp_devices").Select("code_secret").Row().Scan(&secret)
+    assert.False(t, security.IsLegacyCiphertext(secret), "should re-encrypt the legacy secrets")
+
+    _, err = rollback(db, schemaMigrations, 2)
+    assert.Nil(t, err, "should roll back the data migrations")
+    db.Table("sessions").Where("invalidated = ?", false).Count(&count)
+    assert.Equal(t, 0, count, "should invalidate the digested tokens")
+    _, err = rollback(db, schemaMigrations, 1)
+    assert.Nil(t, err, "should roll back the initial schema")
+    assert.False(t, db.HasTable("sessions"), "should drop the tables")
+}
+
+// The initial schema is a snapshot; changes to the models must come with a migration
+func TestSchemaMatchesModels(t *testing.T) {
+    db := testingDataStore(t)
+    _, err := migrate(db, schemaMigrations)
+    assert.Nil(t, err, "should apply the migrations")
+    for _, model := range []interface{}{&models.Client{}, &models.Language{}, &models.User{}, &models.Session{},
+            &models.SigningKey{}, &models.Credential{}, &models.TOTPDevice{}} {
+        scope := db.NewScope(model)
+        for _, field := range scope.GetModelStruct().StructFields {
+            if field.IsNormal && !field.IsIgnored {
+                assert.True(t, db.Dialect().HasColumn(scope.TableName(), field.DBName),
+                    "should have a migration for " + scope.TableName() + "." + field.DBName)
+            }
+        }
+    }
+}
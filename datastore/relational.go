@@ -1,41 +1,102 @@
 package datastore
 
 import (
+    "errors"
     "fmt"
     "github.com/jinzhu/gorm"
+    _ "github.com/jinzhu/gorm/dialects/mysql"
     _ "github.com/jinzhu/gorm/dialects/postgres"
+    _ "github.com/jinzhu/gorm/dialects/sqlite"
 
     "github.com/earaujoassis/space/models"
     "github.com/earaujoassis/space/config"
 )
 
+const (
+    PostgresDriver  string = "postgres"
+    MySQLDriver     string = "mysql"
+    SQLiteDriver    string = "sqlite3"
+)
+
 var dataStore *gorm.DB
 
+// Start refuses to run on a data store with pending migrations; they're applied with `space db migrate`
 func Start() {
-    GetDataStoreConnection().AutoMigrate(&models.Client{},
-        &models.Language{},
-        &models.User{},
-        &models.Session{})
+    dataStore := GetDataStoreConnection()
+    pending, err := pendingMigrations(dataStore, schemaMigrations)
+    if err != nil {
+        panic(fmt.Sprintf("Failed to check the data store migrations: %v\n", err))
+    }
+    if len(pending) > 0 {
+        panic(fmt.Sprintf("The data store has %d pending migrations; run `space db migrate`\n", len(pending)))
+    }
+    // Action tokens are kept in the memory store, which has no migrations
+    models.DigestActionTokens()
+}
+
+// SPACE_DATASTORE_DRIVER selects the relational database: `postgres` (the default), `mysql` or `sqlite3`
+func Driver() string {
+    if driver := config.GetConfig("SPACE_DATASTORE_DRIVER"); driver != "" {
+        return driver
+    }
+    return PostgresDriver
+}
+
+// SQLite databases are files, at SPACE_DATASTORE_PATH; by default, the database name is used as the file name
+func connectionData(driver, databaseName string) (string, error) {
+    switch driver {
+    case PostgresDriver:
+        return fmt.Sprintf("host=%s user=%s dbname=%s sslmode=%s password=%s",
+            config.GetConfig("SPACE_DATASTORE_HOST"),
+            config.GetConfig("SPACE_DATASTORE_USER"),
+            databaseName,
+            config.GetConfig("SPACE_DATASTORE_SSL_MODE"),
+            config.GetConfig("SPACE_DATASTORE_PASSWORD"),
+        ), nil
+    case MySQLDriver:
+        return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
+            config.GetConfig("SPACE_DATASTORE_USER"),
+            config.GetConfig("SPACE_DATASTORE_PASSWORD"),
+            config.GetConfig("SPACE_DATASTORE_HOST"),
+            databaseName,
+        ), nil
+    case SQLiteDriver:
+        if path := config.GetConfig("SPACE_DATASTORE_PATH"); path != "" {
+            return path, nil
+        }
+        return fmt.Sprintf("%v.sqlite3", databaseName), nil
+    }
+    return "", errors.New("Unsupported data store driver")
+}
+
+// Connection data holds the data store password; only the driver, host and database name are printed
+func connectionDescription(driver, databaseName string) string {
+    if driver == SQLiteDriver {
+        path, _ := connectionData(driver, databaseName)
+        return fmt.Sprintf("%s (%s)", driver, path)
+    }
+    return fmt.Sprintf("%s (%s/%s)", driver, config.GetConfig("SPACE_DATASTORE_HOST"), databaseName)
 }
 
 func GetDataStoreConnection() *gorm.DB {
     if dataStore != nil {
         return dataStore
     }
-    var err error
+    var driver = Driver()
     var databaseName = fmt.Sprintf("%v_%v",
         config.GetConfig("SPACE_DATASTORE_NAME_PREFIX"), config.Environment())
-    var databaseConnectionData = fmt.Sprintf("host=%s user=%s dbname=%s sslmode=%s password=%s",
-        config.GetConfig("SPACE_DATASTORE_HOST"),
-        config.GetConfig("SPACE_DATASTORE_USER"),
-        databaseName,
-        config.GetConfig("SPACE_DATASTORE_SSL_MODE"),
-        config.GetConfig("SPACE_DATASTORE_PASSWORD"),
-    )
-    fmt.Printf("Connected to the following data store: %v\n", databaseConnectionData)
-    dataStore, err = gorm.Open("postgres", databaseConnectionData)
+    databaseConnectionData, err := connectionData(driver, databaseName)
     if err != nil {
         panic(fmt.Sprintf("Failed to connect datastore: %v\n", err))
     }
+    fmt.Printf("Connected to the following data store: %v\n", connectionDescription(driver, databaseName))
+    dataStore, err = gorm.Open(driver, databaseConnectionData)
+    if err != nil {
+        panic(fmt.Sprintf("Failed to connect datastore: %v\n", err))
+    }
+    // SQLite allows a single writer; concurrent connections would fail with "database is locked"
+    if driver == SQLiteDriver {
+        dataStore.DB().SetMaxOpenConns(1)
+    }
     return dataStore
 }
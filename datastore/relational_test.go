@@ -0,0 +1,24 @@
+package datastore
+
+import (
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestConnectionDescription(t *testing.T) {
+    os.Setenv("SPACE_DATASTORE_HOST", "db.saturn.com:3306")
+    os.Setenv("SPACE_DATASTORE_USER", "space")
+    os.Setenv("SPACE_DATASTORE_PASSWORD", "s3cr3t-passw0rd")
+    defer os.Unsetenv("SPACE_DATASTORE_HOST")
+    defer os.Unsetenv("SPACE_DATASTORE_USER")
+    defer os.Unsetenv("SPACE_DATASTORE_PASSWORD")
+
+    for _, driver := range []string{PostgresDriver, MySQLDriver} {
+        description := connectionDescription(driver, "space_production")
+        assert.Equal(t, driver + " (db.saturn.com:3306/space_production)", description, "should describe the data store")
+        assert.False(t, strings.Contains(description, "s3cr3t-passw0rd"), "should not print the password")
+    }
+}
@@ -0,0 +1,251 @@
+package datastore
+
+import (
+    "time"
+
+    "github.com/jinzhu/gorm"
+
+    "github.com/earaujoassis/space/models"
+)
+
+// Models are no longer migrated automatically: every change to the schema, or to the data
+// it holds, is a new migration appended to this list
+var schemaMigrations = []Migration{
+    {
+        Version: 1,
+        Name: "create_initial_schema",
+        Up: createInitialSchema,
+        Down: dropInitialSchema,
+    },
+    {
+        Version: 2,
+        Name: "digest_plaintext_tokens",
+        Up: digestPlaintextTokens,
+        Down: invalidateDigestedTokens,
+    },
+    {
+        Version: 3,
+        Name: "reencrypt_legacy_secrets",
+        Up: reencryptLegacySecrets,
+        Down: keepReencryptedSecrets,
+    },
+}
+
+var initialSchemaTables = []string{"clients", "languages", "users", "sessions",
+    "signing_keys", "credentials", "totp_devices"}
+
+// Data stores created with `AutoMigrate` are brought up to date as well.
+// The schema is a snapshot of the models at this version: later changes to them need their own migrations
+func createInitialSchema(db *gorm.DB) error {
+    type client struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        UUID string                 `gorm:"not null;unique;index"`
+        Name string                 `gorm:"not null;unique;index"`
+        Description string          `gorm:"type:text"`
+        Key string                  `gorm:"not null;unique;index"`
+        Secret string               `gorm:"not null"`
+        Scopes string               `gorm:"not null"`
+        CanonicalURI string         `gorm:"not null"`
+        RedirectURI string          `gorm:"not null;type:text"`
+        Type string                 `gorm:"not null"`
+        AccessTokenFormat string    `gorm:"not null;default:'opaque'"`
+    }
+    type language struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        Name string                 `gorm:"not null;unique;index"`
+        IsoCode string              `gorm:"not null;unique"`
+    }
+    type user struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        UUID string                 `gorm:"not null;unique;index"`
+        PublicId string             `gorm:"not null;unique;index"`
+        Username string             `gorm:"not null;unique;index"`
+        FirstName string            `gorm:"not null"`
+        LastName string             `gorm:"not null"`
+        Email string                `gorm:"not null;unique;index"`
+        Passphrase string           `gorm:"not null"`
+        Active bool                 `gorm:"not null;default:false"`
+        Admin bool                  `gorm:"not null;default:false"`
+        ClientID uint               `gorm:"not null"`
+        LanguageID uint             `gorm:"not null"`
+        TimezoneIdentifier string   `gorm:"not null;default:'GMT'"`
+        CodeSecret string           `gorm:"not null"`
+        RecoverSecret string        `gorm:"not null"`
+        ConfirmedAt *time.Time
+        DeactivatedAt *time.Time    `gorm:"index"`
+        AnonymizedAt *time.Time
+    }
+    type session struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        UUID string                 `gorm:"not null;unique;index"`
+        UserID uint                 `gorm:"not null"`
+        ClientID uint               `gorm:"not null"`
+        Moment int64                `gorm:"not null"`
+        ExpiresIn int64             `gorm:"not null;default:0"`
+        Ip string                   `gorm:"not null;index"`
+        UserAgent string            `gorm:"not null"`
+        Invalidated bool            `gorm:"not null;default:false"`
+        Token string                `gorm:"not null;unique;index"`
+        TokenType string            `gorm:"not null;index"`
+        Scopes string               `gorm:"not null"`
+        CodeChallenge string        `gorm:"not null;default:''"`
+        CodeChallengeMethod string  `gorm:"not null;default:''"`
+        ParentID uint               `gorm:"not null;default:0;index"`
+        Nonce string                `gorm:"not null;default:''"`
+    }
+    type signingKey struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        Kid string                  `gorm:"not null;unique;index"`
+        Algorithm string            `gorm:"not null"`
+        Status string               `gorm:"not null;index"`
+        PrivateKey string           `gorm:"not null;type:text"`
+    }
+    type credential struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        UUID string                 `gorm:"not null;unique;index"`
+        UserID uint                 `gorm:"not null;index"`
+        CredentialID string         `gorm:"not null;unique;index"`
+        PublicKey string            `gorm:"not null;type:text"`
+        SignCount int64             `gorm:"not null;default:0"`
+        Transports string           `gorm:"not null;default:''"`
+        Name string                 `gorm:"not null;default:''"`
+        LastUsedAt *time.Time
+    }
+    type totpDevice struct {
+        ID uint                     `gorm:"primary_key"`
+        CreatedAt time.Time         `gorm:"not null"`
+        UpdatedAt time.Time
+        UUID string                 `gorm:"not null;unique;index"`
+        UserID uint                 `gorm:"not null;index"`
+        Name string                 `gorm:"not null;default:''"`
+        CodeSecret string           `gorm:"not null"`
+        LastUsedAt *time.Time
+    }
+
+    activateExistingUsers := db.Dialect().HasTable("users") &&
+        !db.Dialect().HasColumn("users", "deactivated_at")
+    confirmExistingUsers := db.Dialect().HasTable("users") &&
+        !db.Dialect().HasColumn("users", "confirmed_at")
+    snapshots := []interface{}{&client{}, &language{}, &user{}, &session{}, &signingKey{}, &credential{}, &totpDevice{}}
+    for i, snapshot := range snapshots {
+        if err := db.Table(initialSchemaTables[i]).AutoMigrate(snapshot).Error; err != nil {
+            return err
+        }
+    }
+    // `User.Active` was never set before deactivation was introduced; existing users are active
+    if activateExistingUsers {
+        if err := db.Exec("UPDATE users SET active = ?", true).Error; err != nil {
+            return err
+        }
+    }
+    // Users created before the email confirmation was introduced are not required to confirm it
+    if confirmExistingUsers {
+        if err := db.Exec("UPDATE users SET confirmed_at = created_at").Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Rolling back the initial schema drops every table, and every record in them
+func dropInitialSchema(db *gorm.DB) error {
+    for i := len(initialSchemaTables) - 1; i >= 0; i-- {
+        if err := db.DropTableIfExists(initialSchemaTables[i]).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Bearer tokens used to be stored in plaintext; existing rows are converted to their keyed digests
+func digestPlaintextTokens(db *gorm.DB) error {
+    var id uint
+    var token string
+
+    rows, err := db.Table("sessions").Select("id, token").Rows()
+    if err != nil {
+        return err
+    }
+    plaintextTokens := make(map[uint]string)
+    for rows.Next() {
+        if err := rows.Scan(&id, &token); err == nil && !models.IsTokenDigest(token) {
+            plaintextTokens[id] = token
+        }
+    }
+    rows.Close()
+    for id, token := range plaintextTokens {
+        err := db.Table("sessions").Where("id = ?", id).UpdateColumn("token", models.DigestToken(token)).Error
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Digests can't be turned back into tokens: rolling back invalidates the digested sessions instead,
+// since earlier revisions would never match them
+func invalidateDigestedTokens(db *gorm.DB) error {
+    return db.Exec("UPDATE sessions SET invalidated = ?", true).Error
+}
+
+// Secrets in the legacy AES-CFB format (or encrypted with a key other than the primary one)
+// are re-encrypted with the primary storage key. Later rotations of the storage keys are
+// done with `space secrets reencrypt`, since a migration only runs once
+func reencryptLegacySecrets(db *gorm.DB) error {
+    if err := reencryptColumn(db, "users", "code_secret"); err != nil {
+        return err
+    }
+    if err := reencryptColumn(db, "totp_devices", "code_secret"); err != nil {
+        return err
+    }
+    return reencryptColumn(db, "signing_keys", "private_key")
+}
+
+// Every revision with migrations decrypts secrets in the keyring format; there's nothing to revert
+func keepReencryptedSecrets(db *gorm.DB) error {
+    return nil
+}
+
+func reencryptColumn(db *gorm.DB, table, column string) error {
+    var id uint
+    var crypted string
+
+    rows, err := db.Table(table).Select("id, " + column).Rows()
+    if err != nil {
+        return err
+    }
+    recrypted := make(map[uint]string)
+    for rows.Next() {
+        if err := rows.Scan(&id, &crypted); err != nil {
+            rows.Close()
+            return err
+        }
+        secret, changed, err := models.ReencryptSecret(crypted)
+        if err != nil {
+            rows.Close()
+            return err
+        }
+        if changed {
+            recrypted[id] = secret
+        }
+    }
+    rows.Close()
+    for id, secret := range recrypted {
+        if err := db.Table(table).Where("id = ?", id).UpdateColumn(column, secret).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
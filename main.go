@@ -47,7 +47,106 @@ func main() {
                 },
             },
         },
+        {
+            Name:    "keys",
+            Aliases: []string{"k"},
+            Usage:   "Manage token signing keys",
+            Subcommands: []cli.Command{
+                {
+                    Name:  "rotate",
+                    Usage: "Retire the active key, activate the next one and create a new next key",
+                    Flags: []cli.Flag{
+                        cli.StringFlag{
+                            Name:  "algorithm",
+                            Value: "RS256",
+                            Usage: "Signing algorithm for the new key (RS256 or ES256)",
+                        },
+                    },
+                    Action: func(c *cli.Context) error {
+                        tasks.RotateKeys(c.String("algorithm"))
+                        return nil
+                    },
+                },
+            },
+        },
+        {
+            Name:    "users",
+            Aliases: []string{"u"},
+            Usage:   "Manage user accounts",
+            Subcommands: []cli.Command{
+                {
+                    Name:  "purge",
+                    Usage: "Anonymize the users deactivated for longer than the grace period",
+                    Action: func(c *cli.Context) error {
+                        tasks.PurgeUsers()
+                        return nil
+                    },
+                },
+            },
+        },
+        {
+            Name:    "db",
+            Aliases: []string{"d"},
+            Usage:   "Manage the data store schema",
+            Subcommands: []cli.Command{
+                {
+                    Name:  "migrate",
+                    Usage: "Apply the pending migrations",
+                    Action: func(c *cli.Context) error {
+                        if err := tasks.MigrateDataStore(); err != nil {
+                            return cli.NewExitError(err.Error(), 1)
+                        }
+                        return nil
+                    },
+                },
+                {
+                    Name:  "rollback",
+                    Usage: "Revert the latest applied migrations",
+                    Flags: []cli.Flag{
+                        cli.IntFlag{
+                            Name:  "steps",
+                            Value: 1,
+                            Usage: "Number of migrations to revert",
+                        },
+                    },
+                    Action: func(c *cli.Context) error {
+                        if err := tasks.RollbackDataStore(c.Int("steps")); err != nil {
+                            return cli.NewExitError(err.Error(), 1)
+                        }
+                        return nil
+                    },
+                },
+                {
+                    Name:  "status",
+                    Usage: "List the applied and pending migrations",
+                    Action: func(c *cli.Context) error {
+                        if err := tasks.DataStoreStatus(); err != nil {
+                            return cli.NewExitError(err.Error(), 1)
+                        }
+                        return nil
+                    },
+                },
+            },
+        },
+        {
+            Name:    "secrets",
+            Aliases: []string{"x"},
+            Usage:   "Manage secrets stored in the data store",
+            Subcommands: []cli.Command{
+                {
+                    Name:  "reencrypt",
+                    Usage: "Re-encrypt the stored secrets with the primary storage key",
+                    Action: func(c *cli.Context) error {
+                        tasks.ReencryptSecrets()
+                        return nil
+                    },
+                },
+            },
+        },
     }
 
-    app.Run(os.Args)
+    // Actions report failures through `cli.NewExitError`; any other error also exits with a non-zero status
+    if err := app.Run(os.Args); err != nil {
+        os.Exit(1)
+    }
 }
@@ -1,43 +1,276 @@
 package memstore
 
 import (
-    "fmt"
+    "errors"
+    "strconv"
+    "sync"
+    "time"
+)
 
-    "github.com/garyburd/redigo/redis"
+type memoryEntry struct {
+    value string
+    hash map[string]string
+    sortedSet map[string]int64
+    expiresAt time.Time
+}
 
-    "github.com/earaujoassis/space/config"
-)
+// In-process store, safe for concurrent use; data is lost when the process ends and it isn't
+// shared between processes, so it's only suitable for single-node deployments and tests
+type MemoryStore struct {
+    mutex sync.Mutex
+    entries map[string]*memoryEntry
+    now func() time.Time
+}
+
+var errWrongType = errors.New("Operation against a key holding the wrong kind of value")
+
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{entries: make(map[string]*memoryEntry), now: time.Now}
+}
+
+// Expired keys are removed when they're accessed
+func (store *MemoryStore) entry(key string) *memoryEntry {
+    entry, ok := store.entries[key]
+    if !ok {
+        return nil
+    }
+    if !entry.expiresAt.IsZero() && !store.now().Before(entry.expiresAt) {
+        delete(store.entries, key)
+        return nil
+    }
+    return entry
+}
+
+func (store *MemoryStore) hashEntry(key string, create bool) (*memoryEntry, error) {
+    entry := store.entry(key)
+    if entry == nil {
+        if !create {
+            return nil, nil
+        }
+        entry = &memoryEntry{hash: make(map[string]string)}
+        store.entries[key] = entry
+    }
+    if entry.hash == nil {
+        return nil, errWrongType
+    }
+    return entry, nil
+}
+
+func (store *MemoryStore) sortedSetEntry(key string, create bool) (*memoryEntry, error) {
+    entry := store.entry(key)
+    if entry == nil {
+        if !create {
+            return nil, nil
+        }
+        entry = &memoryEntry{sortedSet: make(map[string]int64)}
+        store.entries[key] = entry
+    }
+    if entry.sortedSet == nil {
+        return nil, errWrongType
+    }
+    return entry, nil
+}
+
+func (store *MemoryStore) Get(key string) (string, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry := store.entry(key)
+    if entry == nil {
+        return "", ErrNil
+    }
+    if entry.hash != nil || entry.sortedSet != nil {
+        return "", errWrongType
+    }
+    return entry.value, nil
+}
+
+func (store *MemoryStore) Set(key, value string, expiration int64) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry := &memoryEntry{value: value}
+    if expiration > 0 {
+        entry.expiresAt = store.now().Add(time.Duration(expiration) * time.Second)
+    }
+    store.entries[key] = entry
+    return nil
+}
+
+func (store *MemoryStore) SetNX(key, value string, expiration int64) (bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    if store.entry(key) != nil {
+        return false, nil
+    }
+    entry := &memoryEntry{value: value}
+    if expiration > 0 {
+        entry.expiresAt = store.now().Add(time.Duration(expiration) * time.Second)
+    }
+    store.entries[key] = entry
+    return true, nil
+}
+
+func (store *MemoryStore) Del(keys ...string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    for _, key := range keys {
+        delete(store.entries, key)
+    }
+    return nil
+}
+
+func (store *MemoryStore) HGet(key, field string) (string, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.hashEntry(key, false)
+    if err != nil {
+        return "", err
+    }
+    if entry == nil {
+        return "", ErrNil
+    }
+    value, ok := entry.hash[field]
+    if !ok {
+        return "", ErrNil
+    }
+    return value, nil
+}
 
-var memoryStore redis.Conn
-
-func Start() {
-    var err error
-    var storeURI string
-    if config.IsEnvironment("production") {
-        storeURI = fmt.Sprintf("redis://:%v@%v:%v/%v",
-            config.GetConfig("SPACE_MEMORYSTORE_PASSWORD"),
-            config.GetConfig("SPACE_MEMORYSTORE_HOST"),
-            config.GetConfig("SPACE_MEMORYSTORE_PORT"),
-            config.GetConfig("SPACE_MEMORYSTORE_INDEX"))
-    } else {
-        storeURI = fmt.Sprintf("redis://%v:%v/%v",
-            config.GetConfig("SPACE_MEMORYSTORE_HOST"),
-            config.GetConfig("SPACE_MEMORYSTORE_PORT"),
-            config.GetConfig("SPACE_MEMORYSTORE_INDEX"))
-    }
-    memoryStore, err = redis.DialURL(storeURI)
+func (store *MemoryStore) HSet(key, field, value string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.hashEntry(key, true)
     if err != nil {
-        panic(err)
+        return err
+    }
+    entry.hash[field] = value
+    return nil
+}
+
+func (store *MemoryStore) HDel(key string, fields ...string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.hashEntry(key, false)
+    if entry == nil || err != nil {
+        return err
     }
+    for _, field := range fields {
+        delete(entry.hash, field)
+    }
+    if len(entry.hash) == 0 {
+        delete(store.entries, key)
+    }
+    return nil
 }
 
-func Do(commandName string, args ...interface{}) (reply interface{}, err error) {
-    return memoryStore.Do(commandName, args...)
+func (store *MemoryStore) HExists(key, field string) (bool, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.hashEntry(key, false)
+    if entry == nil || err != nil {
+        return false, err
+    }
+    _, ok := entry.hash[field]
+    return ok, nil
 }
 
-func Close() {
-    if memoryStore != nil {
-        memoryStore.Close()
-        memoryStore = nil
+func (store *MemoryStore) HGetAll(key string) (map[string]string, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    values := make(map[string]string)
+    entry, err := store.hashEntry(key, false)
+    if entry == nil || err != nil {
+        return values, err
+    }
+    for field, value := range entry.hash {
+        values[field] = value
+    }
+    return values, nil
+}
+
+func (store *MemoryStore) HIncrBy(key, field string, increment int64) (int64, error) {
+    var value int64
+
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.hashEntry(key, true)
+    if err != nil {
+        return 0, err
+    }
+    if current, ok := entry.hash[field]; ok {
+        if value, err = strconv.ParseInt(current, 10, 64); err != nil {
+            return 0, errors.New("Hash value is not an integer")
+        }
+    }
+    value += increment
+    entry.hash[field] = strconv.FormatInt(value, 10)
+    return value, nil
+}
+
+func (store *MemoryStore) ZAdd(key string, score int64, member string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.sortedSetEntry(key, true)
+    if err != nil {
+        return err
     }
+    entry.sortedSet[member] = score
+    return nil
+}
+
+func (store *MemoryStore) ZRem(key string, members ...string) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.sortedSetEntry(key, false)
+    if entry == nil || err != nil {
+        return err
+    }
+    for _, member := range members {
+        delete(entry.sortedSet, member)
+    }
+    if len(entry.sortedSet) == 0 {
+        delete(store.entries, key)
+    }
+    return nil
+}
+
+func (store *MemoryStore) ZScore(key, member string) (int64, error) {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.sortedSetEntry(key, false)
+    if err != nil {
+        return 0, err
+    }
+    if entry == nil {
+        return 0, ErrNil
+    }
+    score, ok := entry.sortedSet[member]
+    if !ok {
+        return 0, ErrNil
+    }
+    return score, nil
+}
+
+func (store *MemoryStore) ZRemRangeByScore(key string, min, max int64) error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    entry, err := store.sortedSetEntry(key, false)
+    if entry == nil || err != nil {
+        return err
+    }
+    for member, score := range entry.sortedSet {
+        if score >= min && score <= max {
+            delete(entry.sortedSet, member)
+        }
+    }
+    if len(entry.sortedSet) == 0 {
+        delete(store.entries, key)
+    }
+    return nil
+}
+
+func (store *MemoryStore) Close() error {
+    store.mutex.Lock()
+    defer store.mutex.Unlock()
+    store.entries = make(map[string]*memoryEntry)
+    return nil
 }
@@ -0,0 +1,124 @@
+package memstore
+
+import (
+    "math"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreStrings(t *testing.T) {
+    store := NewMemoryStore()
+    moment := time.Unix(1500000000, 0)
+    store.now = func() time.Time { return moment }
+
+    _, err := store.Get("missing")
+    assert.Equal(t, ErrNil, err, "should return ErrNil for a missing key")
+    assert.Nil(t, store.Set("key", "value", 0), "should set a value")
+    value, err := store.Get("key")
+    assert.Nil(t, err, "should get an existing key")
+    assert.Equal(t, "value", value, "should get the stored value")
+
+    assert.Nil(t, store.Set("expiring", "value", 60), "should set an expiring value")
+    moment = moment.Add(59 * time.Second)
+    _, err = store.Get("expiring")
+    assert.Nil(t, err, "should keep the value before it expires")
+    moment = moment.Add(time.Second)
+    _, err = store.Get("expiring")
+    assert.Equal(t, ErrNil, err, "should expire the value")
+
+    set, err := store.SetNX("exclusive", "first", 60)
+    assert.Nil(t, err, "should set a missing key")
+    assert.True(t, set, "should report the key was set")
+    set, _ = store.SetNX("exclusive", "second", 60)
+    assert.False(t, set, "should not replace an existing key")
+    value, _ = store.Get("exclusive")
+    assert.Equal(t, "first", value, "should keep the first value")
+    moment = moment.Add(60 * time.Second)
+    set, _ = store.SetNX("exclusive", "second", 60)
+    assert.True(t, set, "should set an expired key")
+
+    assert.Nil(t, store.Del("key", "missing"), "should delete keys")
+    _, err = store.Get("key")
+    assert.Equal(t, ErrNil, err, "should have deleted the key")
+}
+
+func TestMemoryStoreHashes(t *testing.T) {
+    store := NewMemoryStore()
+
+    exists, err := store.HExists("hash", "field")
+    assert.Nil(t, err, "should check a missing hash")
+    assert.False(t, exists, "should not find a field in a missing hash")
+    assert.Nil(t, store.HSet("hash", "field", "value"), "should set a field")
+    exists, _ = store.HExists("hash", "field")
+    assert.True(t, exists, "should find an existing field")
+    value, _ := store.HGet("hash", "field")
+    assert.Equal(t, "value", value, "should get the field value")
+    _, err = store.HGet("hash", "missing")
+    assert.Equal(t, ErrNil, err, "should return ErrNil for a missing field")
+
+    count, err := store.HIncrBy("hash", "counter", 1)
+    assert.Nil(t, err, "should increment a missing field")
+    assert.Equal(t, int64(1), count, "should start counting from zero")
+    count, _ = store.HIncrBy("hash", "counter", 2)
+    assert.Equal(t, int64(3), count, "should increment an existing field")
+    _, err = store.HIncrBy("hash", "field", 1)
+    assert.NotNil(t, err, "should refuse to increment a non-integer value")
+
+    all, _ := store.HGetAll("hash")
+    assert.Equal(t, map[string]string{"field": "value", "counter": "3"}, all, "should get every field")
+    assert.Nil(t, store.HDel("hash", "field", "counter"), "should delete fields")
+    all, _ = store.HGetAll("hash")
+    assert.Equal(t, map[string]string{}, all, "should have deleted every field")
+
+    store.Set("string", "value", 0)
+    assert.NotNil(t, store.HSet("string", "field", "value"), "should refuse a key of another kind")
+}
+
+func TestMemoryStoreSortedSets(t *testing.T) {
+    store := NewMemoryStore()
+
+    assert.Nil(t, store.ZAdd("set", 10, "first"), "should add a member")
+    assert.Nil(t, store.ZAdd("set", 20, "second"), "should add another member")
+    assert.Nil(t, store.ZAdd("set", 30, "third"), "should add yet another member")
+    score, err := store.ZScore("set", "second")
+    assert.Nil(t, err, "should find an existing member")
+    assert.Equal(t, int64(20), score, "should return the member score")
+    _, err = store.ZScore("set", "missing")
+    assert.Equal(t, ErrNil, err, "should return ErrNil for a missing member")
+
+    assert.Nil(t, store.ZRemRangeByScore("set", math.MinInt64, 20), "should remove a range of members")
+    _, err = store.ZScore("set", "first")
+    assert.Equal(t, ErrNil, err, "should have removed the members in the range")
+    _, err = store.ZScore("set", "third")
+    assert.Nil(t, err, "should keep the members out of the range")
+    assert.Nil(t, store.ZRem("set", "third"), "should remove a member")
+    _, err = store.ZScore("set", "third")
+    assert.Equal(t, ErrNil, err, "should have removed the member")
+}
+
+func TestMemoryStoreConcurrency(t *testing.T) {
+    var group sync.WaitGroup
+    var setCount int64
+    var setMutex sync.Mutex
+    store := NewMemoryStore()
+
+    for i := 0; i < 50; i++ {
+        group.Add(1)
+        go func() {
+            defer group.Done()
+            store.HIncrBy("hash", "counter", 1)
+            if set, _ := store.SetNX("exclusive", "value", 60); set {
+                setMutex.Lock()
+                setCount++
+                setMutex.Unlock()
+            }
+        }()
+    }
+    group.Wait()
+    value, _ := store.HGet("hash", "counter")
+    assert.Equal(t, "50", value, "should not lose concurrent increments")
+    assert.Equal(t, int64(1), setCount, "should set a missing key only once")
+}
@@ -0,0 +1,178 @@
+package memstore
+
+import (
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/garyburd/redigo/redis"
+
+    "github.com/earaujoassis/space/config"
+)
+
+const (
+    defaultMaxIdle          int = 10
+    defaultMaxActive        int = 100
+    defaultIdleTimeout      int = 240 // seconds
+    defaultTimeout          int = 5   // seconds
+    healthCheckInterval     time.Duration = time.Minute
+)
+
+func storeURI() string {
+    if config.IsEnvironment("production") {
+        return fmt.Sprintf("redis://:%v@%v:%v/%v",
+            config.GetConfig("SPACE_MEMORYSTORE_PASSWORD"),
+            config.GetConfig("SPACE_MEMORYSTORE_HOST"),
+            config.GetConfig("SPACE_MEMORYSTORE_PORT"),
+            config.GetConfig("SPACE_MEMORYSTORE_INDEX"))
+    }
+    return fmt.Sprintf("redis://%v:%v/%v",
+        config.GetConfig("SPACE_MEMORYSTORE_HOST"),
+        config.GetConfig("SPACE_MEMORYSTORE_PORT"),
+        config.GetConfig("SPACE_MEMORYSTORE_INDEX"))
+}
+
+func configuredInt(key string, fallback int) int {
+    if value, err := strconv.Atoi(config.GetConfig(key)); err == nil && value >= 0 {
+        return value
+    }
+    return fallback
+}
+
+// The pool is configured through SPACE_MEMORYSTORE_MAX_IDLE, SPACE_MEMORYSTORE_MAX_ACTIVE,
+// SPACE_MEMORYSTORE_IDLE_TIMEOUT and SPACE_MEMORYSTORE_TIMEOUT (both in seconds); idle
+// connections are checked with a PING before they're reused
+func newPool() *redis.Pool {
+    timeout := time.Duration(configuredInt("SPACE_MEMORYSTORE_TIMEOUT", defaultTimeout)) * time.Second
+    return &redis.Pool{
+        MaxIdle: configuredInt("SPACE_MEMORYSTORE_MAX_IDLE", defaultMaxIdle),
+        MaxActive: configuredInt("SPACE_MEMORYSTORE_MAX_ACTIVE", defaultMaxActive),
+        IdleTimeout: time.Duration(configuredInt("SPACE_MEMORYSTORE_IDLE_TIMEOUT", defaultIdleTimeout)) * time.Second,
+        Wait: true,
+        Dial: func() (redis.Conn, error) {
+            return redis.DialURL(storeURI(),
+                redis.DialConnectTimeout(timeout),
+                redis.DialReadTimeout(timeout),
+                redis.DialWriteTimeout(timeout))
+        },
+        TestOnBorrow: func(conn redis.Conn, lastUsed time.Time) error {
+            if time.Since(lastUsed) < healthCheckInterval {
+                return nil
+            }
+            _, err := conn.Do("PING")
+            return err
+        },
+    }
+}
+
+// Redis-backed store; each command borrows a connection from the pool, so it's safe for concurrent use
+type RedisStore struct {
+    pool *redis.Pool
+}
+
+func NewRedisStore() *RedisStore {
+    return &RedisStore{pool: newPool()}
+}
+
+func (store *RedisStore) do(commandName string, args ...interface{}) (interface{}, error) {
+    conn := store.pool.Get()
+    defer conn.Close()
+    return conn.Do(commandName, args...)
+}
+
+func nilReply(err error) error {
+    if err == redis.ErrNil {
+        return ErrNil
+    }
+    return err
+}
+
+func (store *RedisStore) Ping() error {
+    _, err := store.do("PING")
+    return err
+}
+
+func (store *RedisStore) Get(key string) (string, error) {
+    value, err := redis.String(store.do("GET", key))
+    return value, nilReply(err)
+}
+
+func (store *RedisStore) Set(key, value string, expiration int64) error {
+    if expiration > 0 {
+        _, err := store.do("SET", key, value, "EX", expiration)
+        return err
+    }
+    _, err := store.do("SET", key, value)
+    return err
+}
+
+func (store *RedisStore) SetNX(key, value string, expiration int64) (bool, error) {
+    var reply interface{}
+    var err error
+
+    if expiration > 0 {
+        reply, err = store.do("SET", key, value, "NX", "EX", expiration)
+    } else {
+        reply, err = store.do("SET", key, value, "NX")
+    }
+    if err != nil {
+        return false, err
+    }
+    return reply != nil, nil
+}
+
+func (store *RedisStore) Del(keys ...string) error {
+    _, err := store.do("DEL", redis.Args{}.AddFlat(keys)...)
+    return err
+}
+
+func (store *RedisStore) HGet(key, field string) (string, error) {
+    value, err := redis.String(store.do("HGET", key, field))
+    return value, nilReply(err)
+}
+
+func (store *RedisStore) HSet(key, field, value string) error {
+    _, err := store.do("HSET", key, field, value)
+    return err
+}
+
+func (store *RedisStore) HDel(key string, fields ...string) error {
+    _, err := store.do("HDEL", redis.Args{}.Add(key).AddFlat(fields)...)
+    return err
+}
+
+func (store *RedisStore) HExists(key, field string) (bool, error) {
+    return redis.Bool(store.do("HEXISTS", key, field))
+}
+
+func (store *RedisStore) HGetAll(key string) (map[string]string, error) {
+    return redis.StringMap(store.do("HGETALL", key))
+}
+
+func (store *RedisStore) HIncrBy(key, field string, increment int64) (int64, error) {
+    return redis.Int64(store.do("HINCRBY", key, field, increment))
+}
+
+func (store *RedisStore) ZAdd(key string, score int64, member string) error {
+    _, err := store.do("ZADD", key, score, member)
+    return err
+}
+
+func (store *RedisStore) ZRem(key string, members ...string) error {
+    _, err := store.do("ZREM", redis.Args{}.Add(key).AddFlat(members)...)
+    return err
+}
+
+func (store *RedisStore) ZScore(key, member string) (int64, error) {
+    score, err := redis.Int64(store.do("ZSCORE", key, member))
+    return score, nilReply(err)
+}
+
+func (store *RedisStore) ZRemRangeByScore(key string, min, max int64) error {
+    _, err := store.do("ZREMRANGEBYSCORE", key, min, max)
+    return err
+}
+
+func (store *RedisStore) Close() error {
+    return store.pool.Close()
+}
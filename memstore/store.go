@@ -0,0 +1,161 @@
+package memstore
+
+import (
+    "errors"
+    "sync"
+
+    "github.com/earaujoassis/space/config"
+)
+
+const (
+    RedisDriver     string = "redis"
+    MemoryDriver    string = "memory"
+)
+
+// Returned when a key, a hash field or a sorted set member doesn't exist
+var ErrNil = errors.New("Nil reply")
+
+// Operations on strings, hashes and sorted sets used by models, policy and feature;
+// expirations are in seconds, and zero means the key doesn't expire
+type Store interface {
+    Get(key string) (string, error)
+    Set(key, value string, expiration int64) error
+    // It sets the key only when it doesn't exist, atomically; it reports whether the key was set
+    SetNX(key, value string, expiration int64) (bool, error)
+    Del(keys ...string) error
+
+    HGet(key, field string) (string, error)
+    HSet(key, field, value string) error
+    HDel(key string, fields ...string) error
+    HExists(key, field string) (bool, error)
+    HGetAll(key string) (map[string]string, error)
+    HIncrBy(key, field string, increment int64) (int64, error)
+
+    ZAdd(key string, score int64, member string) error
+    ZRem(key string, members ...string) error
+    ZScore(key, member string) (int64, error)
+    ZRemRangeByScore(key string, min, max int64) error
+
+    Close() error
+}
+
+var store Store
+var storeMutex sync.Mutex
+
+// SPACE_MEMORYSTORE_DRIVER selects the store: `redis` (the default) or `memory`, an in-process
+// store for single-node deployments; the testing environment uses the in-process store by default
+func Driver() string {
+    if driver := config.GetConfig("SPACE_MEMORYSTORE_DRIVER"); driver != "" {
+        return driver
+    }
+    if config.IsEnvironment("testing") {
+        return MemoryDriver
+    }
+    return RedisDriver
+}
+
+func newStore(driver string) (Store, error) {
+    switch driver {
+    case RedisDriver:
+        return NewRedisStore(), nil
+    case MemoryDriver:
+        return NewMemoryStore(), nil
+    }
+    return nil, errors.New("Unsupported memory store driver")
+}
+
+func currentStore() Store {
+    storeMutex.Lock()
+    defer storeMutex.Unlock()
+    if store == nil {
+        var err error
+        if store, err = newStore(Driver()); err != nil {
+            panic(err)
+        }
+    }
+    return store
+}
+
+// The store is created on its first use; Start creates it upfront and checks it's available
+func Start() {
+    if redisStore, ok := currentStore().(*RedisStore); ok {
+        if err := redisStore.Ping(); err != nil {
+            panic(err)
+        }
+    }
+}
+
+// Replaces the current store; mostly useful for tests
+func Use(replacement Store) {
+    storeMutex.Lock()
+    defer storeMutex.Unlock()
+    if store != nil {
+        store.Close()
+    }
+    store = replacement
+}
+
+func Close() {
+    storeMutex.Lock()
+    defer storeMutex.Unlock()
+    if store != nil {
+        store.Close()
+        store = nil
+    }
+}
+
+func Get(key string) (string, error) {
+    return currentStore().Get(key)
+}
+
+func Set(key, value string, expiration int64) error {
+    return currentStore().Set(key, value, expiration)
+}
+
+func SetNX(key, value string, expiration int64) (bool, error) {
+    return currentStore().SetNX(key, value, expiration)
+}
+
+func Del(keys ...string) error {
+    return currentStore().Del(keys...)
+}
+
+func HGet(key, field string) (string, error) {
+    return currentStore().HGet(key, field)
+}
+
+func HSet(key, field, value string) error {
+    return currentStore().HSet(key, field, value)
+}
+
+func HDel(key string, fields ...string) error {
+    return currentStore().HDel(key, fields...)
+}
+
+func HExists(key, field string) (bool, error) {
+    return currentStore().HExists(key, field)
+}
+
+func HGetAll(key string) (map[string]string, error) {
+    return currentStore().HGetAll(key)
+}
+
+func HIncrBy(key, field string, increment int64) (int64, error) {
+    return currentStore().HIncrBy(key, field, increment)
+}
+
+func ZAdd(key string, score int64, member string) error {
+    return currentStore().ZAdd(key, score, member)
+}
+
+func ZRem(key string, members ...string) error {
+    return currentStore().ZRem(key, members...)
+}
+
+func ZScore(key, member string) (int64, error) {
+    return currentStore().ZScore(key, member)
+}
+
+func ZRemRangeByScore(key string, min, max int64) error {
+    return currentStore().ZRemRangeByScore(key, min, max)
+}
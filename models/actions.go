@@ -4,8 +4,8 @@ import (
     "time"
     "encoding/json"
 
-    "github.com/garyburd/redigo/redis"
     "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/security"
 )
 
 type Action struct {
@@ -28,30 +28,33 @@ func (action *Action) Save() error {
     action.ClientID = action.Client.ID
     action.UUID = generateUUID()
     action.CreatedAt = time.Now().UTC()
-    action.Token = GenerateRandomString(64)
+    token, err := security.GenerateToken(64)
+    if err != nil {
+        return err
+    }
+    action.Token = DigestToken(token)
     action.Moment = time.Now().UTC().Unix()
     action.ExpiresIn = shortestExpirationLength
     if err := validateModel("validate", action); err != nil {
         return err
     }
-    memstore.Start()
-    defer memstore.Close()
     actionJson, _ := json.Marshal(action)
-    memstore.Do("HSET", "models.actions", action.UUID, actionJson)
-    memstore.Do("HSET", "models.actions.indexes", action.Token, action.UUID)
-    memstore.Do("ZADD", "models.actions.rank", action.Moment, action.UUID)
+    memstore.HSet("models.actions", action.UUID, string(actionJson))
+    memstore.HSet("models.actions.indexes", action.Token, action.UUID)
+    memstore.ZAdd("models.actions.rank", action.Moment, action.UUID)
+    // Only the digest is stored; the plain token is handed out right after its creation
+    action.Token = token
     return nil
 }
 
 func (action *Action) Delete() {
-    memstore.Start()
-    defer memstore.Close()
-    if actionExists, _ := redis.Bool(memstore.Do("HEXISTS", "models.actions", action.UUID)); !actionExists {
+    storedAction := RetrieveActionByUUID(action.UUID)
+    if storedAction.UUID == "" {
         return
     }
-    memstore.Do("HDEL", "models.actions.indexes", action.Token)
-    memstore.Do("HDEL", "models.actions", action.UUID)
-    memstore.Do("ZREM", "models.actions.rank", action.UUID)
+    memstore.HDel("models.actions.indexes", storedAction.Token)
+    memstore.HDel("models.actions", action.UUID)
+    memstore.ZRem("models.actions.rank", action.UUID)
 }
 
 func (action *Action) WithinExpirationWindow() bool {
@@ -61,12 +64,10 @@ func (action *Action) WithinExpirationWindow() bool {
 
 func RetrieveActionByUUID(uuid string) Action {
     var action Action
-    memstore.Start()
-    defer memstore.Close()
-    if actionExists, _ := redis.Bool(memstore.Do("HEXISTS", "models.actions", uuid)); !actionExists {
+    if actionExists, _ := memstore.HExists("models.actions", uuid); !actionExists {
         return Action{}
     }
-    actionString, _ := redis.String(memstore.Do("HGET", "models.actions", uuid))
+    actionString, _ := memstore.HGet("models.actions", uuid)
     if err := json.Unmarshal([]byte(actionString), &action); err != nil {
         return Action{}
     }
@@ -74,11 +75,33 @@ func RetrieveActionByUUID(uuid string) Action {
 }
 
 func RetrieveActionByToken(token string) Action {
-    memstore.Start()
-    defer memstore.Close()
-    if indexExists, _ := redis.Bool(memstore.Do("HEXISTS", "models.actions.indexes", token)); !indexExists {
+    token = DigestToken(token)
+    if indexExists, _ := memstore.HExists("models.actions.indexes", token); !indexExists {
         return Action{}
     }
-    actionUUID, _ := redis.String(memstore.Do("HGET", "models.actions.indexes", token))
+    actionUUID, _ := memstore.HGet("models.actions.indexes", token)
     return RetrieveActionByUUID(actionUUID)
 }
+
+// Actions used to be indexed by their plain tokens; they are re-indexed by their digests
+func DigestActionTokens() {
+    indexes, _ := memstore.HGetAll("models.actions.indexes")
+    for token, actionUUID := range indexes {
+        if IsTokenDigest(token) {
+            continue
+        }
+        memstore.HDel("models.actions.indexes", token)
+        actionString, err := memstore.HGet("models.actions", actionUUID)
+        if err != nil {
+            continue
+        }
+        var action Action
+        if err := json.Unmarshal([]byte(actionString), &action); err != nil {
+            continue
+        }
+        action.Token = DigestToken(token)
+        actionJson, _ := json.Marshal(action)
+        memstore.HSet("models.actions", action.UUID, string(actionJson))
+        memstore.HSet("models.actions.indexes", action.Token, action.UUID)
+    }
+}
@@ -4,25 +4,30 @@ import (
     "strings"
 
     "github.com/jinzhu/gorm"
-    "golang.org/x/crypto/bcrypt"
+
+    "github.com/earaujoassis/space/security"
 )
 
 const (
     PublicClient        string = "public"
     ConfidentialClient  string = "confidential"
+
+    OpaqueAccessToken   string = "opaque"
+    JWTAccessToken      string = "jwt"
 )
 
 type Client struct {
     Model
     UUID string                 `gorm:"not null;unique;index" validate:"omitempty,uuid4" json:"id"`
     Name string                 `gorm:"not null;unique;index" validate:"required,min=3,max=20" json:"name"`
-    Description string          `json:"description"`
+    Description string          `gorm:"type:text" json:"description"`
     Key string                  `gorm:"not null;unique;index" json:"-"`
     Secret string               `gorm:"not null" validate:"required" json:"-"`
     Scopes string               `gorm:"not null" validate:"required" json:"-"`
     CanonicalURI string         `gorm:"not null" validate:"required" json:"uri"`
-    RedirectURI string          `gorm:"not null" validate:"required" json:"-"`
+    RedirectURI string          `gorm:"not null;type:text" validate:"required" json:"-"`
     Type string                 `gorm:"not null" validate:"required,client" json:"-"`
+    AccessTokenFormat string    `gorm:"not null;default:'opaque'" validate:"omitempty,token_format" json:"-"`
 }
 
 func validClientType(top interface{}, current interface{}, field interface{}, param string) bool {
@@ -33,32 +38,64 @@ func validClientType(top interface{}, current interface{}, field interface{}, pa
     return true
 }
 
+func validAccessTokenFormat(top interface{}, current interface{}, field interface{}, param string) bool {
+    format := field.(string)
+    if format != OpaqueAccessToken && format != JWTAccessToken {
+        return false
+    }
+    return true
+}
+
+// On success, a secret hash weaker than the configured hasher is replaced in the model;
+// see services.ClientAuthentication, which stores it
 func (client *Client) Authentic(secret string) bool {
-    validSecret := bcrypt.CompareHashAndPassword([]byte(client.Secret), []byte(secret)) == nil
+    validSecret, rehashed := verifySecret(client.Secret, secret)
+    if validSecret && rehashed != "" {
+        client.Secret = rehashed
+    }
     return validSecret
 }
 
 func (client *Client) UpdateSecret(secret string) error {
-    crypted, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+    crypted, err := hashSecret(secret)
     if err == nil {
-        client.Secret = string(crypted)
+        client.Secret = crypted
         return nil
     }
     return err
 }
 
-func (client *Client) BeforeSave(scope *gorm.Scope) error {
+func (client *Client) Validate() error {
     return validateModel("validate", client)
 }
 
+func (client *Client) BeforeSave(scope *gorm.Scope) error {
+    return client.Validate()
+}
+
+// Generates the identifiers of a new client and hashes its secret
+func (client *Client) Prepare() error {
+    key, err := security.GenerateToken(32)
+    if err != nil {
+        return err
+    }
+    crypted, err := hashSecret(client.Secret)
+    if err != nil {
+        return err
+    }
+    client.UUID = generateUUID()
+    client.Key = key
+    client.Secret = crypted
+    return nil
+}
+
 func (client *Client) BeforeCreate(scope *gorm.Scope) error {
-    scope.SetColumn("UUID", generateUUID())
-    scope.SetColumn("Key", GenerateRandomString(32))
-    if crypted, err := bcrypt.GenerateFromPassword([]byte(client.Secret), bcrypt.DefaultCost); err == nil {
-        scope.SetColumn("Secret", crypted)
-    } else {
+    if err := client.Prepare(); err != nil {
         return err
     }
+    scope.SetColumn("UUID", client.UUID)
+    scope.SetColumn("Key", client.Key)
+    scope.SetColumn("Secret", client.Secret)
     return nil
 }
 
@@ -0,0 +1,44 @@
+package models
+
+import (
+    "encoding/base64"
+    "time"
+
+    "github.com/jinzhu/gorm"
+)
+
+// WebAuthn credentials (security keys), used as a second factor; ids and public keys (COSE)
+// are kept in URL-safe base64, as used by WebAuthn clients
+type Credential struct {
+    Model
+    UUID string                 `gorm:"not null;unique;index" validate:"omitempty,uuid4" json:"id"`
+    User User                   `gorm:"not null" validate:"exists" json:"-"`
+    UserID uint                 `gorm:"not null;index" json:"-"`
+    CredentialID string         `gorm:"not null;unique;index" validate:"required" json:"credential_id"`
+    PublicKey string            `gorm:"not null;type:text" validate:"required" json:"-"`
+    SignCount int64             `gorm:"not null;default:0" json:"-"`
+    Transports string           `gorm:"not null;default:''" json:"transports"`
+    Name string                 `gorm:"not null;default:''" validate:"max=60" json:"name"`
+    LastUsedAt *time.Time       `json:"last_used_at"`
+}
+
+func EncodeCredentialData(data []byte) string {
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCredentialData(data string) ([]byte, error) {
+    return base64.RawURLEncoding.DecodeString(data)
+}
+
+func (credential *Credential) PublicKeyData() ([]byte, error) {
+    return DecodeCredentialData(credential.PublicKey)
+}
+
+func (credential *Credential) BeforeSave(scope *gorm.Scope) error {
+    return validateModel("validate", credential)
+}
+
+func (credential *Credential) BeforeCreate(scope *gorm.Scope) error {
+    scope.SetColumn("UUID", generateUUID())
+    return nil
+}
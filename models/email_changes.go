@@ -0,0 +1,65 @@
+package models
+
+import (
+    "fmt"
+    "time"
+    "encoding/json"
+
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/security"
+)
+
+// An email change is only applied once the new email is confirmed, through a token sent to it
+type EmailChange struct {
+    UUID string                 `validate:"omitempty,uuid4" json:"uuid"`
+    UserID uint                 `validate:"required" json:"user_id"`
+    Email string                `validate:"required,email" json:"email"`
+    Token string                `validate:"omitempty,alphanum" json:"token"`
+    Moment int64                `json:"moment"`
+    ExpiresIn int64             `json:"expires_in"`
+}
+
+func emailChangeKey(digest string) string {
+    return fmt.Sprintf("models.email_changes.%s", digest)
+}
+
+func (change *EmailChange) Save() error {
+    token, err := security.GenerateToken(64)
+    if err != nil {
+        return err
+    }
+    change.UUID = generateUUID()
+    change.Token = DigestToken(token)
+    change.Moment = time.Now().UTC().Unix()
+    change.ExpiresIn = confirmationExpirationLength
+    if err := validateModel("validate", change); err != nil {
+        return err
+    }
+    changeJson, _ := json.Marshal(change)
+    memstore.Set(emailChangeKey(change.Token), string(changeJson), change.ExpiresIn)
+    // Only the digest is stored; the plain token is handed out right after its creation
+    change.Token = token
+    return nil
+}
+
+func (change *EmailChange) Delete() {
+    memstore.Del(emailChangeKey(DigestToken(change.Token)))
+}
+
+func (change *EmailChange) WithinExpirationWindow() bool {
+    now := time.Now().UTC().Unix()
+    return change.Moment + change.ExpiresIn >= now
+}
+
+func RetrieveEmailChangeByToken(token string) EmailChange {
+    var change EmailChange
+    changeString, err := memstore.Get(emailChangeKey(DigestToken(token)))
+    if err != nil {
+        return EmailChange{}
+    }
+    if err := json.Unmarshal([]byte(changeString), &change); err != nil {
+        return EmailChange{}
+    }
+    change.Token = token
+    return change
+}
@@ -1,13 +1,16 @@
 package models
 
 import (
+    "fmt"
+    "strconv"
+    "sync"
     "time"
-    "math/rand"
 
     "github.com/satori/go.uuid"
     "gopkg.in/bluesuncorp/validator.v5"
 
     "github.com/earaujoassis/space/config"
+    "github.com/earaujoassis/space/security"
 )
 
 type Model struct {
@@ -16,32 +19,6 @@ type Model struct {
     UpdatedAt time.Time         `json:"-"`
 }
 
-const (
-    letterBytes   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-    letterIdxBits = 6                    // 6 bits to represent a letter index
-    letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-    letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
-)
-
-var src = rand.NewSource(time.Now().UnixNano())
-
-func GenerateRandomString(n int) string {
-    b := make([]byte, n)
-    for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
-        if remain == 0 {
-            cache, remain = src.Int63(), letterIdxMax
-        }
-        if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
-            b[i] = letterBytes[idx]
-            i--
-        }
-        cache >>= letterIdxBits
-        remain--
-    }
-
-    return string(b)
-}
-
 func generateUUID() string {
     return uuid.NewV4().String()
 }
@@ -51,6 +28,8 @@ func validateModel(tagName string, model interface{}) error {
     validate.AddFunction("client", validClientType)
     validate.AddFunction("scope", validScope)
     validate.AddFunction("token", validTokenType)
+    validate.AddFunction("key_status", validKeyStatus)
+    validate.AddFunction("token_format", validAccessTokenFormat)
     err := validate.Struct(model)
     if err != nil {
         return err
@@ -70,3 +49,145 @@ func defaultKey() []byte {
     keyString := config.GetConfig("SPACE_STORAGE_SECRET")
     return []byte(keyString)
 }
+
+var storageKeyring struct {
+    sync.Once
+    keyring *security.Keyring
+    err error
+}
+
+// Secrets are encrypted with the keyring from SPACE_STORAGE_KEYS; without it, SPACE_STORAGE_SECRET
+// is the only key. SPACE_STORAGE_SECRET is also used to decrypt secrets in the legacy format
+func secretsKeyring() (*security.Keyring, error) {
+    storageKeyring.Do(func() {
+        if keys := config.GetConfig("SPACE_STORAGE_KEYS"); keys != "" {
+            storageKeyring.keyring, storageKeyring.err = security.ParseKeyring(keys, defaultKey())
+        } else {
+            storageKeyring.keyring, storageKeyring.err = security.NewKeyring("default",
+                map[string][]byte{"default": defaultKey()}, defaultKey())
+        }
+    })
+    return storageKeyring.keyring, storageKeyring.err
+}
+
+func encryptSecret(secret []byte) (string, error) {
+    keyring, err := secretsKeyring()
+    if err != nil {
+        return "", err
+    }
+    return keyring.Encrypt(secret)
+}
+
+func decryptSecret(crypted string) ([]byte, error) {
+    keyring, err := secretsKeyring()
+    if err != nil {
+        return nil, err
+    }
+    return keyring.Decrypt(crypted)
+}
+
+// Re-encrypts a secret with the primary key, when it uses the legacy format or another key.
+// Empty secrets (e.g. of anonymized users) have nothing to re-encrypt
+func ReencryptSecret(crypted string) (string, bool, error) {
+    if crypted == "" {
+        return crypted, false, nil
+    }
+    keyring, err := secretsKeyring()
+    if err != nil {
+        return "", false, err
+    }
+    if !keyring.NeedsReencryption(crypted) {
+        return crypted, false, nil
+    }
+    secret, err := keyring.Decrypt(crypted)
+    if err != nil {
+        return "", false, err
+    }
+    recrypted, err := keyring.Encrypt(secret)
+    if err != nil {
+        return "", false, err
+    }
+    return recrypted, true, nil
+}
+
+var secretsHasher struct {
+    sync.Once
+    hasher security.Hasher
+    err error
+}
+
+func configuredUint(key string, fallback uint64, bits int) (uint64, error) {
+    value := config.GetConfig(key)
+    if value == "" {
+        return fallback, nil
+    }
+    parsed, err := strconv.ParseUint(value, 10, bits)
+    if err != nil || parsed == 0 {
+        return 0, fmt.Errorf("Invalid %s: %s", key, value)
+    }
+    return parsed, nil
+}
+
+// Passphrases and client secrets are hashed with SPACE_PASSWORD_HASH (`bcrypt`, the default, or
+// `argon2id`); SPACE_BCRYPT_COST, SPACE_ARGON2_PASSES, SPACE_ARGON2_MEMORY (KiB) and
+// SPACE_ARGON2_LANES set its parameters
+func passwordHasher() (security.Hasher, error) {
+    secretsHasher.Do(func() {
+        var cost, passes, memory, lanes uint64
+        var err error
+
+        hasher := security.DefaultHasher()
+        if algorithm := config.GetConfig("SPACE_PASSWORD_HASH"); algorithm != "" {
+            hasher.Algorithm = algorithm
+        }
+        if cost, err = configuredUint("SPACE_BCRYPT_COST", uint64(hasher.BcryptCost), 8); err != nil {
+            secretsHasher.err = err
+            return
+        }
+        if passes, err = configuredUint("SPACE_ARGON2_PASSES", uint64(hasher.Argon2Passes), 32); err != nil {
+            secretsHasher.err = err
+            return
+        }
+        if memory, err = configuredUint("SPACE_ARGON2_MEMORY", uint64(hasher.Argon2Memory), 32); err != nil {
+            secretsHasher.err = err
+            return
+        }
+        if lanes, err = configuredUint("SPACE_ARGON2_LANES", uint64(hasher.Argon2Lanes), 8); err != nil {
+            secretsHasher.err = err
+            return
+        }
+        hasher.BcryptCost = int(cost)
+        hasher.Argon2Passes, hasher.Argon2Memory, hasher.Argon2Lanes = uint32(passes), uint32(memory), uint8(lanes)
+        secretsHasher.hasher, secretsHasher.err = hasher, hasher.Validate()
+    })
+    return secretsHasher.hasher, secretsHasher.err
+}
+
+// The application checks the password hasher configuration when it starts
+func ValidatePasswordHasher() error {
+    _, err := passwordHasher()
+    return err
+}
+
+func hashSecret(secret string) (string, error) {
+    hasher, err := passwordHasher()
+    if err != nil {
+        return "", err
+    }
+    return hasher.Hash([]byte(secret))
+}
+
+// Verifies a secret against its hash; when the hash is weaker than the configured hasher,
+// it also returns a new hash for the secret
+func verifySecret(hashed, secret string) (bool, string) {
+    hasher, err := passwordHasher()
+    if err != nil || !hasher.Verify(hashed, []byte(secret)) {
+        return false, ""
+    }
+    if hasher.NeedsRehash(hashed) {
+        if rehashed, err := hasher.Hash([]byte(secret)); err == nil {
+            return true, rehashed
+        }
+    }
+    return true, ""
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+    "time"
+    "encoding/json"
+
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/security"
+)
+
+// Password resets are one-time and short-lived; they are deleted once used
+type PasswordReset struct {
+    UUID string                 `validate:"omitempty,uuid4" json:"uuid"`
+    User User                   `validate:"exists" json:"-"`
+    UserID uint                 `json:"user_id"`
+    Moment int64                `json:"moment"`
+    ExpiresIn int64             `json:"expires_in"`
+    Ip string                   `validate:"required" json:"ip"`
+    UserAgent string            `validate:"required" json:"user_agent"`
+    Token string                `validate:"omitempty,alphanum" json:"token"`
+    CreatedAt time.Time         `json:"created_at"`
+}
+
+func (reset *PasswordReset) Save() error {
+    reset.UserID = reset.User.ID
+    reset.UUID = generateUUID()
+    reset.CreatedAt = time.Now().UTC()
+    token, err := security.GenerateToken(64)
+    if err != nil {
+        return err
+    }
+    reset.Token = DigestToken(token)
+    reset.Moment = time.Now().UTC().Unix()
+    reset.ExpiresIn = defaultExpirationLength
+    if err := validateModel("validate", reset); err != nil {
+        return err
+    }
+    resetJson, _ := json.Marshal(reset)
+    memstore.HSet("models.password_resets", reset.UUID, string(resetJson))
+    memstore.HSet("models.password_resets.indexes", reset.Token, reset.UUID)
+    memstore.ZAdd("models.password_resets.rank", reset.Moment, reset.UUID)
+    // Only the digest is stored; the plain token is handed out right after its creation
+    reset.Token = token
+    return nil
+}
+
+func (reset *PasswordReset) Delete() {
+    storedReset := RetrievePasswordResetByUUID(reset.UUID)
+    if storedReset.UUID == "" {
+        return
+    }
+    memstore.HDel("models.password_resets.indexes", storedReset.Token)
+    memstore.HDel("models.password_resets", reset.UUID)
+    memstore.ZRem("models.password_resets.rank", reset.UUID)
+}
+
+func (reset *PasswordReset) WithinExpirationWindow() bool {
+    now := time.Now().UTC().Unix()
+    return reset.Moment + reset.ExpiresIn >= now
+}
+
+func RetrievePasswordResetByUUID(uuid string) PasswordReset {
+    var reset PasswordReset
+    if resetExists, _ := memstore.HExists("models.password_resets", uuid); !resetExists {
+        return PasswordReset{}
+    }
+    resetString, _ := memstore.HGet("models.password_resets", uuid)
+    if err := json.Unmarshal([]byte(resetString), &reset); err != nil {
+        return PasswordReset{}
+    }
+    return reset
+}
+
+func RetrievePasswordResetByToken(token string) PasswordReset {
+    token = DigestToken(token)
+    if indexExists, _ := memstore.HExists("models.password_resets.indexes", token); !indexExists {
+        return PasswordReset{}
+    }
+    resetUUID, _ := memstore.HGet("models.password_resets.indexes", token)
+    return RetrievePasswordResetByUUID(resetUUID)
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+    "math"
+    "time"
+
+    "github.com/earaujoassis/space/memstore"
+)
+
+// Self-contained (JWT) access tokens are validated without the data store; their
+// identifiers (jti) are kept in a revocation list until they expire
+func RevokeAccessToken(jti string, expiresAt int64) {
+    memstore.ZRemRangeByScore("models.sessions.revoked", math.MinInt64, time.Now().UTC().Unix())
+    memstore.ZAdd("models.sessions.revoked", expiresAt, jti)
+}
+
+// Tokens are also considered revoked when the revocation list is unavailable
+func AccessTokenRevoked(jti string) bool {
+    _, err := memstore.ZScore("models.sessions.revoked", jti)
+    return err != memstore.ErrNil
+}
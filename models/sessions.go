@@ -1,9 +1,13 @@
 package models
 
 import (
+    "errors"
+    "strings"
     "time"
 
     "github.com/jinzhu/gorm"
+
+    "github.com/earaujoassis/space/security"
 )
 
 const (
@@ -14,12 +18,13 @@ const (
     PublicScope               string = "public"
     ReadScope                 string = "read"
     ReadWriteScope            string = "read_write"
+    OpenIDScope               string = "openid"
 )
 
 type Session struct {
     Model
     UUID string                 `gorm:"not null;unique;index" validate:"omitempty,uuid4" json:"-"`
-    User User                   `gorm:"not null" validate:"exists" json:"-"`
+    User User                   `validate:"-" json:"-"`
     UserID uint                 `gorm:"not null" json:"-"`
     Client Client               `gorm:"not null" validate:"exists" json:"-"`
     ClientID uint               `gorm:"not null" json:"-"`
@@ -31,16 +36,36 @@ type Session struct {
     Token string                `gorm:"not null;unique;index" validate:"omitempty,alphanum" json:"token"`
     TokenType string            `gorm:"not null;index" validate:"required,token" json:"token_type"`
     Scopes string               `gorm:"not null" validate:"required,scope" json:"-"`
+    CodeChallenge string        `gorm:"not null;default:''" json:"-"`
+    CodeChallengeMethod string  `gorm:"not null;default:''" json:"-"`
+    ParentID uint               `gorm:"not null;default:0;index" json:"-"`
+    Nonce string                `gorm:"not null;default:''" json:"-"`
+    plainToken string
 }
 
+// Scopes are space-delimited (RFC 6749, section 3.3)
 func validScope(top interface{}, current interface{}, field interface{}, param string) bool {
-    scope := field.(string)
-    if scope != PublicScope && scope != ReadScope && scope != ReadWriteScope {
+    scopes := strings.Fields(field.(string))
+    if len(scopes) == 0 {
         return false
     }
+    for _, scope := range scopes {
+        if scope != PublicScope && scope != ReadScope && scope != ReadWriteScope && scope != OpenIDScope {
+            return false
+        }
+    }
     return true
 }
 
+func ScopeIncludes(scopes, scope string) bool {
+    for _, included := range strings.Fields(scopes) {
+        if included == scope {
+            return true
+        }
+    }
+    return false
+}
+
 func validTokenType(top interface{}, current interface{}, field interface{}, param string) bool {
     tokenType := field.(string)
     if tokenType != AccessToken && tokenType != RefreshToken && tokenType != GrantToken {
@@ -62,15 +87,50 @@ func expirationLengthForTokenType(tokenType string) int64 {
     }
 }
 
-func (session *Session) BeforeSave(scope *gorm.Scope) error {
+func (session *Session) Validate() error {
+    if !session.HasUser() && session.TokenType != AccessToken {
+        return errors.New("Only access tokens may be issued without an user")
+    }
     return validateModel("validate", session)
 }
 
+func (session *Session) BeforeSave(scope *gorm.Scope) error {
+    return session.Validate()
+}
+
+// Generates the identifiers and the token of a new session; only the token digest is stored
+func (session *Session) Prepare() error {
+    token, err := security.GenerateToken(64)
+    if err != nil {
+        return err
+    }
+    session.plainToken = token
+    session.Token = DigestToken(token)
+    session.UUID = generateUUID()
+    session.Moment = time.Now().UTC().Unix()
+    session.ExpiresIn = expirationLengthForTokenType(session.TokenType)
+    return nil
+}
+
 func (session *Session) BeforeCreate(scope *gorm.Scope) error {
-    scope.SetColumn("Token", GenerateRandomString(64))
-    scope.SetColumn("UUID", generateUUID())
-    scope.SetColumn("Moment", time.Now().UTC().Unix())
-    scope.SetColumn("ExpiresIn", expirationLengthForTokenType(session.TokenType))
+    if err := session.Prepare(); err != nil {
+        return err
+    }
+    scope.SetColumn("Token", session.Token)
+    scope.SetColumn("UUID", session.UUID)
+    scope.SetColumn("Moment", session.Moment)
+    scope.SetColumn("ExpiresIn", session.ExpiresIn)
+    return nil
+}
+
+// The plain token is handed out right after the creation
+func (session *Session) RevealToken() {
+    session.Token = session.plainToken
+    session.plainToken = ""
+}
+
+func (session *Session) AfterCreate(scope *gorm.Scope) error {
+    session.RevealToken()
     return nil
 }
 
@@ -78,3 +138,8 @@ func (session *Session) WithinExpirationWindow() bool {
     now := time.Now().UTC().Unix()
     return session.ExpiresIn == eternalExpirationLength || session.Moment + session.ExpiresIn >= now
 }
+
+// Sessions issued through the Client Credentials Grant are not bound to any user
+func (session *Session) HasUser() bool {
+    return session.UserID != 0 || session.User.ID != 0 || session.User.PublicId != ""
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+    "crypto"
+
+    "github.com/jinzhu/gorm"
+
+    "github.com/earaujoassis/space/security"
+)
+
+const (
+    ActiveKey                 string = "active"
+    NextKey                   string = "next"
+    RetiredKey                string = "retired"
+
+    // Retired keys are kept while the tokens they signed may be valid; access and ID tokens last up to an hour
+    RetiredKeyLifetime        int64 = largestExpirationLength
+)
+
+type SigningKey struct {
+    Model
+    Kid string                  `gorm:"not null;unique;index" validate:"required" json:"kid"`
+    Algorithm string            `gorm:"not null" validate:"required" json:"alg"`
+    Status string               `gorm:"not null;index" validate:"required,key_status" json:"status"`
+    PrivateKey string           `gorm:"not null;type:text" validate:"required" json:"-"`
+}
+
+func validKeyStatus(top interface{}, current interface{}, field interface{}, param string) bool {
+    status := field.(string)
+    if status != ActiveKey && status != NextKey && status != RetiredKey {
+        return false
+    }
+    return true
+}
+
+// Creates a new key pair; the private key is kept encrypted at rest
+func GenerateSigningKey(algorithm, status string) (SigningKey, error) {
+    privateKey, err := security.GeneratePrivateKey(algorithm)
+    if err != nil {
+        return SigningKey{}, err
+    }
+    pemData, err := security.MarshalPrivateKey(privateKey)
+    if err != nil {
+        return SigningKey{}, err
+    }
+    cryptedPrivateKey, err := encryptSecret(pemData)
+    if err != nil {
+        return SigningKey{}, err
+    }
+    return SigningKey{
+        Kid: security.KeyThumbprint(privateKey.Public()),
+        Algorithm: algorithm,
+        Status: status,
+        PrivateKey: cryptedPrivateKey,
+    }, nil
+}
+
+func (key *SigningKey) Signer() (crypto.Signer, error) {
+    pemData, err := decryptSecret(key.PrivateKey)
+    if err != nil {
+        return nil, err
+    }
+    return security.ParsePrivateKey(pemData)
+}
+
+func (key *SigningKey) ReencryptPrivateKey() (bool, error) {
+    crypted, changed, err := ReencryptSecret(key.PrivateKey)
+    if err != nil || !changed {
+        return false, err
+    }
+    key.PrivateKey = crypted
+    return true, nil
+}
+
+func (key *SigningKey) BeforeSave(scope *gorm.Scope) error {
+    return validateModel("validate", key)
+}
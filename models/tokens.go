@@ -1,12 +1,48 @@
 package models
 
+import (
+    "regexp"
+    "time"
+
+    "github.com/earaujoassis/space/config"
+    "github.com/earaujoassis/space/security"
+)
+
 const (
-    eternalExpirationLength    int64 = 0
-    largestExpirationLength    int64 = 3600 // 60 min
-    defaultExpirationLength    int64 = 1800 // 30 min
-    shortestExpirationLength   int64 = 300  //  5 min
+    eternalExpirationLength         int64 = 0
+    confirmationExpirationLength    int64 = 86400 // 24 hours
+    largestExpirationLength         int64 = 3600  // 60 min
+    defaultExpirationLength         int64 = 1800  // 30 min
+    shortestExpirationLength        int64 = 300   //  5 min
 )
 
 type Tokens interface {
     WithinExpirationWindow()
 }
+
+var tokenDigestPattern = regexp.MustCompile("^[a-f0-9]{64}$")
+
+func tokenDigestKey() []byte {
+    if keyString := config.GetConfig("SPACE_TOKEN_SECRET"); keyString != "" {
+        return []byte(keyString)
+    }
+    return defaultKey()
+}
+
+// Bearer tokens are never stored in plaintext; only their keyed digests are
+func DigestToken(token string) string {
+    return security.TokenDigest(tokenDigestKey(), token)
+}
+
+func IsTokenDigest(value string) bool {
+    return tokenDigestPattern.MatchString(value)
+}
+
+// Expiring tokens are signed instead of stored
+func signExpiringToken(purpose, value string, expiresIn int64) string {
+    return security.SignExpiringValue(tokenDigestKey(), purpose, value, time.Now().UTC().Unix() + expiresIn)
+}
+
+func verifyExpiringToken(purpose, token string) (string, error) {
+    return security.VerifyExpiringValue(tokenDigestKey(), purpose, token)
+}
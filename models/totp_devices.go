@@ -0,0 +1,147 @@
+package models
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "time"
+
+    "github.com/jinzhu/gorm"
+    "github.com/pquerna/otp"
+    "github.com/pquerna/otp/totp"
+
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/security"
+)
+
+const (
+    totpStepExpirationLength    int64 = 90 // the validation window (three time steps)
+)
+
+// Additional authenticators (TOTP) enrolled by an user; the user's own code secret remains
+// the primary authenticator
+type TOTPDevice struct {
+    Model
+    UUID string                 `gorm:"not null;unique;index" validate:"omitempty,uuid4" json:"id"`
+    User User                   `gorm:"not null" validate:"exists" json:"-"`
+    UserID uint                 `gorm:"not null;index" json:"-"`
+    Name string                 `gorm:"not null;default:''" validate:"max=60" json:"name"`
+    CodeSecret string           `gorm:"not null" validate:"required" json:"-"`
+    LastUsedAt *time.Time       `json:"last_used_at"`
+}
+
+func totpEnrollmentKey(userUUID string) string {
+    return fmt.Sprintf("models.totp_devices.enrollments.%s", userUUID)
+}
+
+func totpStepKey(owner string) string {
+    return fmt.Sprintf("models.totp_steps.%s", owner)
+}
+
+func totpClaimKey(owner string, step int64) string {
+    return fmt.Sprintf("models.totp_steps.%s.%d", owner, step)
+}
+
+// Unreadable steps are treated as the latest possible one, so no passcode is accepted
+func parseStep(value string) int64 {
+    step, err := strconv.ParseInt(value, 10, 64)
+    if err != nil {
+        return math.MaxInt64
+    }
+    return step
+}
+
+// A passcode is accepted only once: each time step is claimed atomically, so concurrent replays
+// can't both pass, and passcodes for a time step earlier than the last accepted one are refused
+func acceptPasscodeStep(owner string, step int64) bool {
+    lastStep, err := memstore.Get(totpStepKey(owner))
+    if err == nil && step < parseStep(lastStep) {
+        return false
+    }
+    claimed, claimErr := memstore.SetNX(totpClaimKey(owner, step), "1", totpStepExpirationLength)
+    if claimErr != nil || !claimed {
+        return false
+    }
+    if err == nil && step <= parseStep(lastStep) {
+        return true
+    }
+    if err := memstore.Set(totpStepKey(owner), strconv.FormatInt(step, 10), totpStepExpirationLength); err != nil {
+        return false
+    }
+    return true
+}
+
+func authenticPasscode(owner, cryptedCodeSecret, passcode string) bool {
+    codeSecret, err := decryptSecret(cryptedCodeSecret)
+    if err != nil {
+        return false
+    }
+    step, ok := security.ValidateTOTP(passcode, string(codeSecret), time.Now().UTC())
+    if !ok {
+        return false
+    }
+    return acceptPasscodeStep(owner, step)
+}
+
+// Enrollment secrets are kept (encrypted) until they're confirmed with a passcode
+func StartTOTPEnrollment(user User) (*otp.Key, error) {
+    key, err := totp.Generate(totp.GenerateOpts{
+        Issuer:      "QuatroLabs.com",
+        AccountName: user.Username,
+    })
+    if err != nil {
+        return nil, err
+    }
+    cryptedCodeSecret, err := encryptSecret([]byte(key.Secret()))
+    if err != nil {
+        return nil, err
+    }
+    if err := memstore.Set(totpEnrollmentKey(user.UUID), cryptedCodeSecret, shortestExpirationLength); err != nil {
+        return nil, err
+    }
+    return key, nil
+}
+
+// Confirms the pending enrollment of an user; the returned device is not yet stored
+func ConfirmTOTPEnrollment(user User, name, passcode string) (TOTPDevice, bool) {
+    cryptedCodeSecret, err := memstore.Get(totpEnrollmentKey(user.UUID))
+    if err != nil {
+        return TOTPDevice{}, false
+    }
+    device := TOTPDevice{
+        UUID: generateUUID(),
+        User: user,
+        UserID: user.ID,
+        Name: name,
+        CodeSecret: cryptedCodeSecret,
+    }
+    if !device.AuthenticPasscode(passcode) {
+        return TOTPDevice{}, false
+    }
+    memstore.Del(totpEnrollmentKey(user.UUID))
+    return device, true
+}
+
+func (device *TOTPDevice) AuthenticPasscode(passcode string) bool {
+    return authenticPasscode(fmt.Sprintf("devices.%s", device.UUID), device.CodeSecret, passcode)
+}
+
+func (device *TOTPDevice) ReencryptCodeSecret() (bool, error) {
+    crypted, changed, err := ReencryptSecret(device.CodeSecret)
+    if err != nil || !changed {
+        return false, err
+    }
+    device.CodeSecret = crypted
+    return true, nil
+}
+
+func (device *TOTPDevice) BeforeSave(scope *gorm.Scope) error {
+    return validateModel("validate", device)
+}
+
+func (device *TOTPDevice) BeforeCreate(scope *gorm.Scope) error {
+    if device.UUID == "" {
+        scope.SetColumn("UUID", generateUUID())
+    }
+    return nil
+}
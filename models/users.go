@@ -3,8 +3,8 @@ package models
 import (
     "fmt"
     "strings"
+    "time"
 
-    "golang.org/x/crypto/bcrypt"
     "github.com/jinzhu/gorm"
     "github.com/pquerna/otp"
     "github.com/pquerna/otp/totp"
@@ -12,6 +12,10 @@ import (
     "github.com/earaujoassis/space/security"
 )
 
+const (
+    userConfirmationPurpose string = "user.confirm"
+)
+
 type User struct {
     Model
     UUID string                 `gorm:"not null;unique;index" validate:"omitempty,uuid4" json:"-"`
@@ -20,7 +24,7 @@ type User struct {
     FirstName string            `gorm:"not null" validate:"required,min=3,max=20" essential:"required,min=3,max=20" json:"first_name"`
     LastName string             `gorm:"not null" validate:"required,min=3,max=20" essential:"required,min=3,max=20" json:"last_name"`
     Email string                `gorm:"not null;unique;index" validate:"required,email" essential:"required,email" json:"email"`
-    Passphrase string           `gorm:"not null" validate:"required" essential:"required,min=10" json:"-"`
+    Passphrase string           `gorm:"not null" validate:"required" essential:"required" json:"-"`
     Active bool                 `gorm:"not null;default:false" json:"active"`
     Admin bool                  `gorm:"not null;default:false" json:"-"`
     Client Client               `gorm:"not null" validate:"exists" json:"-"`
@@ -30,23 +34,55 @@ type User struct {
     TimezoneIdentifier string   `gorm:"not null;default:'GMT'" json:"timezone_identifier"`
     CodeSecret string           `gorm:"not null" validate:"required" json:"-"`
     RecoverSecret string        `gorm:"not null" validate:"required" json:"-"`
+    ConfirmedAt *time.Time      `json:"-"`
+    DeactivatedAt *time.Time    `gorm:"index" json:"-"`
+    AnonymizedAt *time.Time     `json:"-"`
 }
 
-func (user *User) Authentic(password, passcode string) bool {
-    validPassword := bcrypt.CompareHashAndPassword([]byte(user.Passphrase), []byte(password)) == nil
-    var validPasscode bool
-    if codeSecret, err := security.Decrypt(defaultKey(), user.CodeSecret); err != nil {
-        return false
-    } else {
-        validPasscode = totp.Validate(passcode, string(codeSecret))
+// Users are activated once their email is confirmed
+func (user *User) Confirm() {
+    now := time.Now().UTC()
+    user.Active = true
+    user.ConfirmedAt = &now
+}
+
+func (user *User) Confirmed() bool {
+    return user.ConfirmedAt != nil
+}
+
+func (user *User) ConfirmationToken() string {
+    return signExpiringToken(userConfirmationPurpose, user.UUID, confirmationExpirationLength)
+}
+
+func UserUUIDFromConfirmationToken(token string) (string, error) {
+    return verifyExpiringToken(userConfirmationPurpose, token)
+}
+
+func (user *User) Deactivate() {
+    now := time.Now().UTC()
+    user.Active = false
+    user.DeactivatedAt = &now
+}
+
+// On success, a passphrase hash weaker than the configured hasher is replaced in the model;
+// see services.AuthenticPassword, which stores it
+func (user *User) AuthenticPassword(password string) bool {
+    valid, rehashed := verifySecret(user.Passphrase, password)
+    if valid && rehashed != "" {
+        user.Passphrase = rehashed
     }
-    return validPasscode && validPassword
+    return valid
+}
+
+// Only the primary authenticator; see services.AuthenticPasscode for enrolled TOTP devices
+func (user *User) AuthenticPasscode(passcode string) bool {
+    return authenticPasscode(fmt.Sprintf("users.%s", user.UUID), user.CodeSecret, passcode)
 }
 
 func (user *User) UpdatePassword(password string) error {
-    crypted, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    crypted, err := hashSecret(password)
     if err == nil {
-        user.Passphrase = string(crypted)
+        user.Passphrase = crypted
         return nil
     }
     return err
@@ -58,7 +94,7 @@ func (user *User) GenerateCodeSecret() *otp.Key {
         AccountName: user.Username,
     })
     codeSecret := key.Secret()
-    if cryptedCodeSecret, err := security.Encrypt(defaultKey(), []byte(codeSecret)); err == nil {
+    if cryptedCodeSecret, err := encryptSecret([]byte(codeSecret)); err == nil {
         user.CodeSecret = string(cryptedCodeSecret)
     } else {
         user.CodeSecret = codeSecret
@@ -69,32 +105,81 @@ func (user *User) GenerateCodeSecret() *otp.Key {
     return key
 }
 
-func (user *User) GenerateRecoverSecret() string {
-    var secret string = strings.ToUpper(fmt.Sprintf("%s-%s-%s-%s",
-        GenerateRandomString(4),
-        GenerateRandomString(4),
-        GenerateRandomString(4),
-        GenerateRandomString(4),))
+func (user *User) ReencryptCodeSecret() (bool, error) {
+    crypted, changed, err := ReencryptSecret(user.CodeSecret)
+    if err != nil || !changed {
+        return false, err
+    }
+    user.CodeSecret = crypted
+    return true, nil
+}
+
+func (user *User) GenerateRecoverSecret() (string, error) {
+    random, err := security.GenerateRandomString(16, security.UpperAlphanumericAlphabet)
+    if err != nil {
+        return "", err
+    }
+    var secret string = fmt.Sprintf("%s-%s-%s-%s", random[0:4], random[4:8], random[8:12], random[12:16])
     user.RecoverSecret = secret
-    return secret
+    return secret, nil
 }
 
-func (user *User) BeforeSave(scope *gorm.Scope) error {
+// Recover secrets are stored hashed; a new secret is only returned once, in plaintext
+func (user *User) UpdateRecoverSecret() (string, error) {
+    secret, err := user.GenerateRecoverSecret()
+    if err != nil {
+        return "", err
+    }
+    crypted, err := hashSecret(secret)
+    if err != nil {
+        return "", err
+    }
+    user.RecoverSecret = crypted
+    return secret, nil
+}
+
+func (user *User) AuthenticRecoverSecret(secret string) bool {
+    secret = strings.ToUpper(strings.TrimSpace(secret))
+    valid, _ := verifySecret(user.RecoverSecret, secret)
+    return valid
+}
+
+func (user *User) Validate() error {
     return validateModel("validate", user)
 }
 
-func (user *User) BeforeCreate(scope *gorm.Scope) error {
-    scope.SetColumn("UUID", generateUUID())
-    scope.SetColumn("PublicId", GenerateRandomString(32))
-    if cryptedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Passphrase), bcrypt.DefaultCost); err == nil {
-        scope.SetColumn("Passphrase", cryptedPassword)
-    } else {
+func (user *User) BeforeSave(scope *gorm.Scope) error {
+    return user.Validate()
+}
+
+// Generates the identifiers of a new user and hashes their passphrase and recover secret
+func (user *User) Prepare() error {
+    publicId, err := security.GenerateToken(32)
+    if err != nil {
         return err
     }
-    if cryptedRecoverSecret, err := bcrypt.GenerateFromPassword([]byte(user.RecoverSecret), bcrypt.DefaultCost); err == nil {
-        scope.SetColumn("RecoverSecret", cryptedRecoverSecret)
-    } else {
+    cryptedPassword, err := hashSecret(user.Passphrase)
+    if err != nil {
+        return err
+    }
+    cryptedRecoverSecret, err := hashSecret(user.RecoverSecret)
+    if err != nil {
+        return err
+    }
+    user.UUID = generateUUID()
+    user.PublicId = publicId
+    user.Passphrase = cryptedPassword
+    user.RecoverSecret = cryptedRecoverSecret
+    return nil
+}
+
+func (user *User) BeforeCreate(scope *gorm.Scope) error {
+    if err := user.Prepare(); err != nil {
         return err
     }
+    scope.SetColumn("UUID", user.UUID)
+    scope.SetColumn("PublicId", user.PublicId)
+    scope.SetColumn("Passphrase", user.Passphrase)
+    scope.SetColumn("RecoverSecret", user.RecoverSecret)
     return nil
 }
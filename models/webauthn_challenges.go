@@ -0,0 +1,42 @@
+package models
+
+import (
+    "fmt"
+
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/security"
+)
+
+const (
+    RegistrationCeremony      string = "registration"
+    AuthenticationCeremony    string = "authentication"
+)
+
+func webAuthnChallengeKey(ceremony, userUUID, challenge string) string {
+    return fmt.Sprintf("models.webauthn.challenges.%s.%s.%s", ceremony, userUUID, challenge)
+}
+
+// Challenges are short-lived and bound to a ceremony and an user; each ceremony has its own
+// challenge, so requesting a new one doesn't cancel a ceremony in progress
+func SaveWebAuthnChallenge(ceremony, userUUID string) (string, error) {
+    challenge, err := security.GenerateURLSafeToken(32)
+    if err != nil {
+        return "", err
+    }
+    if err := memstore.Set(webAuthnChallengeKey(ceremony, userUUID, challenge), "1", shortestExpirationLength); err != nil {
+        return "", err
+    }
+    return challenge, nil
+}
+
+// Challenges are used only once; it reports whether the challenge was pending
+func ConsumeWebAuthnChallenge(ceremony, userUUID, challenge string) bool {
+    if challenge == "" {
+        return false
+    }
+    if _, err := memstore.Get(webAuthnChallengeKey(ceremony, userUUID, challenge)); err != nil {
+        return false
+    }
+    memstore.Del(webAuthnChallengeKey(ceremony, userUUID, challenge))
+    return true
+}
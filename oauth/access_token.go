@@ -14,6 +14,8 @@ func AccessTokenRequest(data utils.H) (utils.H, error) {
 
     var code string
     var redirectURI string
+    var codeVerifier string
+    var issuer string
 
     if data["code"] == nil || data["redirect_uri"] == nil || data["client"] == nil {
         return invalidRequestResult("")
@@ -23,6 +25,14 @@ func AccessTokenRequest(data utils.H) (utils.H, error) {
     code = data["code"].(string)
     client = data["client"].(models.Client)
 
+    if data["code_verifier"] != nil {
+        codeVerifier = data["code_verifier"].(string)
+    }
+
+    if data["issuer"] != nil {
+        issuer = data["issuer"].(string)
+    }
+
     authorizationSession := services.FindSessionByToken(code, models.GrantToken)
     defer services.InvalidateSession(authorizationSession)
     if authorizationSession.ID == 0 {
@@ -30,38 +40,55 @@ func AccessTokenRequest(data utils.H) (utils.H, error) {
     }
     user = authorizationSession.User
     user = services.FindUserByPublicId(user.PublicId)
-    if authorizationSession.Client.ID != client.ID {
+    if authorizationSession.Client.ID != client.ID || !user.Active {
         return invalidGrantResult("")
     }
     if !strings.Contains(authorizationSession.Client.RedirectURI, redirectURI) {
         return invalidGrantResult("")
     }
+    if authorizationSession.CodeChallenge != "" {
+        if !validCodeVerifier(codeVerifier, authorizationSession.CodeChallenge, authorizationSession.CodeChallengeMethod) {
+            return invalidGrantResult("")
+        }
+    } else if client.Type == models.PublicClient {
+        return invalidGrantResult("")
+    }
 
-    accessToken := services.CreateSession(user,
-        client,
-        authorizationSession.Ip,
-        authorizationSession.UserAgent,
-        authorizationSession.Scopes,
-        models.AccessToken)
     refreshToken := services.CreateSession(user,
         client,
         authorizationSession.Ip,
         authorizationSession.UserAgent,
         authorizationSession.Scopes,
         models.RefreshToken)
+    accessToken := services.CreateChildSession(refreshToken, models.AccessToken)
 
     if accessToken.ID == 0 || refreshToken.ID == 0 {
         return serverErrorResult("")
     }
+    accessTokenString, err := accessTokenValue(accessToken, client, issuer)
+    if err != nil {
+        return serverErrorResult("")
+    }
 
-    return utils.H{
+    result := utils.H{
         "user_id": user.PublicId,
-        "access_token": accessToken.Token,
+        "access_token": accessTokenString,
         "token_type": "Bearer",
         "expires_in": accessToken.ExpiresIn,
         "refresh_token": refreshToken.Token,
         "scope": authorizationSession.Scopes,
-    }, nil
+    }
+
+    // OpenID Connect Core 1.0, section 3.1.3.3
+    if models.ScopeIncludes(authorizationSession.Scopes, models.OpenIDScope) {
+        idToken, err := createIDToken(user, client, authorizationSession.Nonce, issuer)
+        if err != nil {
+            return serverErrorResult("")
+        }
+        result["id_token"] = idToken
+    }
+
+    return result, nil
 }
 
 func RefreshTokenRequest(data utils.H) (utils.H, error) {
@@ -70,6 +97,7 @@ func RefreshTokenRequest(data utils.H) (utils.H, error) {
 
     var token string
     var scope string
+    var issuer string
 
     if data["refresh_token"] == nil || data["scope"] == nil || data["client"] == nil {
         return invalidRequestResult("")
@@ -79,6 +107,10 @@ func RefreshTokenRequest(data utils.H) (utils.H, error) {
     scope = data["scope"].(string)
     client = data["client"].(models.Client)
 
+    if data["issuer"] != nil {
+        issuer = data["issuer"].(string)
+    }
+
     refreshSession := services.FindSessionByToken(token, models.RefreshToken)
     defer services.InvalidateSession(refreshSession)
     if refreshSession.ID == 0 {
@@ -86,33 +118,32 @@ func RefreshTokenRequest(data utils.H) (utils.H, error) {
     }
     user = refreshSession.User
     user = services.FindUserByPublicId(user.PublicId)
-    if refreshSession.Client.ID != client.ID {
+    if refreshSession.Client.ID != client.ID || !user.Active {
         return invalidGrantResult("")
     }
     if scope != refreshSession.Scopes {
         return invalidScopeResult("")
     }
 
-    accessToken := services.CreateSession(user,
-        client,
-        refreshSession.Ip,
-        refreshSession.UserAgent,
-        scope,
-        models.AccessToken)
     refreshToken := services.CreateSession(user,
         client,
         refreshSession.Ip,
         refreshSession.UserAgent,
         scope,
         models.RefreshToken)
+    accessToken := services.CreateChildSession(refreshToken, models.AccessToken)
 
     if accessToken.ID == 0 || refreshToken.ID == 0 {
         return serverErrorResult("")
     }
+    accessTokenString, err := accessTokenValue(accessToken, client, issuer)
+    if err != nil {
+        return serverErrorResult("")
+    }
 
     return utils.H{
         "user_id": user.PublicId,
-        "access_token": accessToken.Token,
+        "access_token": accessTokenString,
         "token_type": "Bearer",
         "expires_in": accessToken.ExpiresIn,
         "refresh_token": refreshToken.Token,
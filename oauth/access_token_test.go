@@ -0,0 +1,120 @@
+package oauth
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/utils"
+)
+
+func TestAccessTokenRequest(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+    user, _ := createUser(t)
+
+    grant, _ := AuthorizationCodeGrant(authorizationCodeData(user, client))
+    data := utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+    }
+    result, err := AccessTokenRequest(data)
+    assert.Nil(t, err, "should exchange the authorization code")
+    assert.Equal(t, user.PublicId, result["user_id"], "should return the user")
+    assert.Equal(t, "Bearer", result["token_type"], "should return a bearer token")
+    assert.Equal(t, models.ReadScope, result["scope"], "should return the granted scope")
+    session := AccessAuthentication(result["access_token"].(string))
+    assert.NotEqual(t, uint(0), session.ID, "should issue a valid access token")
+    assert.Equal(t, user.ID, session.User.ID, "should issue the access token to the user")
+    assert.Equal(t, client.ID, session.Client.ID, "should issue the access token for the client")
+
+    result, err = AccessTokenRequest(data)
+    assert.NotNil(t, err, "should not exchange an authorization code twice")
+    assert.Equal(t, InvalidGrant, result["error"], "should return invalid_grant")
+}
+
+func TestAccessTokenRequestForAnotherClient(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+    user, _ := createUser(t)
+    anotherClient := createClient(t, "Mars", models.ConfidentialClient)
+
+    grant, _ := AuthorizationCodeGrant(authorizationCodeData(user, client))
+    result, err := AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": anotherClient,
+    })
+    assert.NotNil(t, err, "should not exchange a code granted to another client")
+    assert.Equal(t, InvalidGrant, result["error"], "should return invalid_grant")
+}
+
+func TestAccessTokenRequestWithCodeVerifier(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.PublicClient)
+    user, _ := createUser(t)
+
+    // Example from RFC 7636, appendix B
+    data := authorizationCodeData(user, client)
+    data["code_challenge"] = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+    data["code_challenge_method"] = S256CodeChallenge
+    grant, _ := AuthorizationCodeGrant(data)
+    result, err := AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+        "code_verifier": "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXkX",
+    })
+    assert.NotNil(t, err, "should not exchange the code with a wrong verifier")
+    assert.Equal(t, InvalidGrant, result["error"], "should return invalid_grant")
+
+    grant, _ = AuthorizationCodeGrant(data)
+    result, err = AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+        "code_verifier": "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+    })
+    assert.Nil(t, err, "should exchange the code with the right verifier")
+    assert.NotEmpty(t, result["access_token"], "should return an access token")
+}
+
+func TestRefreshTokenRequest(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+    user, _ := createUser(t)
+
+    grant, _ := AuthorizationCodeGrant(authorizationCodeData(user, client))
+    tokens, _ := AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+    })
+    data := utils.H{
+        "refresh_token": tokens["refresh_token"],
+        "scope": models.PublicScope,
+        "client": client,
+    }
+    result, err := RefreshTokenRequest(data)
+    assert.NotNil(t, err, "should not refresh a token for another scope")
+    assert.Equal(t, InvalidScope, result["error"], "should return invalid_scope")
+
+    grant, _ = AuthorizationCodeGrant(authorizationCodeData(user, client))
+    tokens, _ = AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+    })
+    data["refresh_token"] = tokens["refresh_token"]
+    data["scope"] = models.ReadScope
+    result, err = RefreshTokenRequest(data)
+    assert.Nil(t, err, "should refresh the token")
+    assert.NotEqual(t, tokens["access_token"], result["access_token"], "should issue a new access token")
+    assert.NotEqual(t, tokens["refresh_token"], result["refresh_token"], "should issue a new refresh token")
+
+    result, err = RefreshTokenRequest(data)
+    assert.NotNil(t, err, "should not use a refresh token twice")
+    assert.Equal(t, InvalidGrant, result["error"], "should return invalid_grant")
+}
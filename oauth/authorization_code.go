@@ -5,6 +5,7 @@ import (
 
     "github.com/earaujoassis/space/utils"
     "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
     "github.com/earaujoassis/space/models"
 )
 
@@ -12,6 +13,10 @@ func AuthorizationCodeGrant(data utils.H) (utils.H, error) {
     var redirectURI string
     var scope string
     var state string
+    var codeChallenge string
+    var codeChallengeMethod string
+    var nonce string
+    var openID bool
 
     var ip string
     var userAgent string
@@ -43,10 +48,39 @@ func AuthorizationCodeGrant(data utils.H) (utils.H, error) {
         scope = data["scope"].(string)
     }
 
+    if data["openid"] != nil {
+        openID = data["openid"].(bool)
+    }
+
+    if data["nonce"] != nil {
+        nonce = data["nonce"].(string)
+    }
+
+    if data["code_challenge"] != nil {
+        codeChallenge = data["code_challenge"].(string)
+    }
+
+    if data["code_challenge_method"] != nil {
+        codeChallengeMethod = data["code_challenge_method"].(string)
+    }
+
     if !strings.Contains(client.RedirectURI, redirectURI) {
         return invalidRedirectURIResult(state)
     }
 
+    // Public clients must use PKCE (RFC 7636); the default challenge method is "plain"
+    if codeChallenge == "" && client.Type == models.PublicClient {
+        return invalidRequestResult(state)
+    }
+    if codeChallenge != "" {
+        if codeChallengeMethod == "" {
+            codeChallengeMethod = PlainCodeChallenge
+        }
+        if !security.ValidCodeChallenge(codeChallenge) || !validCodeChallengeMethod(codeChallengeMethod) {
+            return invalidRequestResult(state)
+        }
+    }
+
     /*
      * WARNING
      * It will grant access, but with a public-only scope
@@ -54,8 +88,11 @@ func AuthorizationCodeGrant(data utils.H) (utils.H, error) {
     if scope != "" && !strings.Contains(client.Scopes, scope) {
         scope = models.PublicScope
     }
+    if openID {
+        scope = strings.Join([]string{scope, models.OpenIDScope}, " ")
+    }
 
-    session := services.CreateSession(user, client, ip, userAgent, scope, models.GrantToken)
+    session := services.CreateGrantSession(user, client, ip, userAgent, scope, codeChallenge, codeChallengeMethod, nonce)
     if session.ID > 0 {
         return utils.H{
             "code": session.Token,
@@ -0,0 +1,57 @@
+package oauth
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/utils"
+)
+
+func authorizationCodeData(user models.User, client models.Client) utils.H {
+    return utils.H{
+        "redirect_uri": testRedirectURI,
+        "user": user,
+        "client": client,
+        "scope": models.ReadScope,
+        "state": "jupiter",
+        "ip": "127.0.0.1",
+        "userAgent": "Testing",
+    }
+}
+
+func TestAuthorizationCodeGrant(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+    user, _ := createUser(t)
+
+    result, err := AuthorizationCodeGrant(authorizationCodeData(user, client))
+    assert.Nil(t, err, "should grant an authorization code")
+    assert.NotEmpty(t, result["code"], "should return the authorization code")
+    assert.Equal(t, "jupiter", result["state"], "should return the state")
+    assert.Equal(t, models.ReadScope, result["scope"], "should return the granted scope")
+
+    data := authorizationCodeData(user, client)
+    data["redirect_uri"] = "https://mars.com/callback"
+    result, err = AuthorizationCodeGrant(data)
+    assert.NotNil(t, err, "should not grant a code for an unknown redirect URI")
+    assert.Equal(t, InvalidRedirectURI, result["error"], "should return invalid_redirect_uri")
+}
+
+func TestAuthorizationCodeGrantForPublicClients(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.PublicClient)
+    user, _ := createUser(t)
+
+    result, err := AuthorizationCodeGrant(authorizationCodeData(user, client))
+    assert.NotNil(t, err, "should require a code challenge from public clients")
+    assert.Equal(t, InvalidRequest, result["error"], "should return invalid_request")
+
+    data := authorizationCodeData(user, client)
+    data["code_challenge"] = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+    data["code_challenge_method"] = S256CodeChallenge
+    result, err = AuthorizationCodeGrant(data)
+    assert.Nil(t, err, "should grant a code with a code challenge")
+    assert.NotEmpty(t, result["code"], "should return the authorization code")
+}
@@ -10,3 +10,25 @@ func ClientAuthentication(authorizationHeader string) models.Client {
     key, secret := utils.BasicAuthDecode(authorizationHeader)
     return services.ClientAuthentication(key, secret)
 }
+
+// Public clients are not able to keep a secret; they must rely on PKCE (RFC 7636)
+func PublicClientAuthentication(key string) models.Client {
+    client := services.FindClientByKey(key)
+    if client.ID != 0 && client.Type == models.PublicClient {
+        return client
+    }
+    return models.Client{}
+}
+
+// Confidential clients authenticate with HTTP Basic. Without it, public clients are identified by
+// their client_id (RFC 6749, section 2.3.1) when publicAllowed: exchanging a code with PKCE, refreshing
+// or revoking the tokens they were issued
+func RequestingClient(authorizationHeader, clientID string, publicAllowed bool) models.Client {
+    if authorizationHeader == "" && clientID != "" {
+        if !publicAllowed {
+            return models.Client{}
+        }
+        return PublicClientAuthentication(clientID)
+    }
+    return ClientAuthentication(authorizationHeader)
+}
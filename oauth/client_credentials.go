@@ -0,0 +1,78 @@
+package oauth
+
+import (
+    "strings"
+
+    "github.com/earaujoassis/space/utils"
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/models"
+)
+
+func ClientCredentialsRequest(data utils.H) (utils.H, error) {
+    var client models.Client
+
+    var scope string
+    var issuer string
+
+    var ip string
+    var userAgent string
+
+    if data["client"] == nil {
+        return invalidRequestResult("")
+    }
+
+    if data["ip"] != nil {
+        ip = data["ip"].(string)
+    }
+
+    if data["userAgent"] != nil {
+        userAgent = data["userAgent"].(string)
+    }
+
+    if data["scope"] != nil {
+        scope = data["scope"].(string)
+    }
+
+    if data["issuer"] != nil {
+        issuer = data["issuer"].(string)
+    }
+
+    client = data["client"].(models.Client)
+
+    if client.Type != models.ConfidentialClient {
+        return unauthorizedClientResult("")
+    }
+
+    if scope == "" {
+        scope = models.PublicScope
+    }
+    if scope != models.PublicScope && scope != models.ReadScope && scope != models.ReadWriteScope {
+        return invalidScopeResult("")
+    }
+    if scope != models.PublicScope && !strings.Contains(client.Scopes, scope) {
+        return invalidScopeResult("")
+    }
+
+    // There's no resource owner for this grant; only an access token is issued (RFC 6749, section 4.4.3)
+    accessToken := services.CreateSession(models.User{},
+        client,
+        ip,
+        userAgent,
+        scope,
+        models.AccessToken)
+
+    if accessToken.ID == 0 {
+        return serverErrorResult("")
+    }
+    accessTokenString, err := accessTokenValue(accessToken, client, issuer)
+    if err != nil {
+        return serverErrorResult("")
+    }
+
+    return utils.H{
+        "access_token": accessTokenString,
+        "token_type": "Bearer",
+        "expires_in": accessToken.ExpiresIn,
+        "scope": scope,
+    }, nil
+}
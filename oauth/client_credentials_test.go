@@ -0,0 +1,36 @@
+package oauth
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/utils"
+)
+
+func TestClientCredentialsRequest(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+
+    result, err := ClientCredentialsRequest(utils.H{
+        "client": client,
+        "scope": models.ReadScope,
+        "ip": "127.0.0.1",
+        "userAgent": "Testing",
+    })
+    assert.Nil(t, err, "should issue an access token to confidential clients")
+    assert.Nil(t, result["refresh_token"], "should not issue a refresh token")
+    session := AccessAuthentication(result["access_token"].(string))
+    assert.NotEqual(t, uint(0), session.ID, "should issue a valid access token")
+    assert.False(t, session.HasUser(), "should not bind the access token to any user")
+
+    result, err = ClientCredentialsRequest(utils.H{"client": client, "scope": "write"})
+    assert.NotNil(t, err, "should not accept an unknown scope")
+    assert.Equal(t, InvalidScope, result["error"], "should return invalid_scope")
+
+    publicClient := createClient(t, "Mars", models.PublicClient)
+    result, err = ClientCredentialsRequest(utils.H{"client": publicClient})
+    assert.NotNil(t, err, "should not issue access tokens to public clients")
+    assert.Equal(t, UnauthorizedClient, result["error"], "should return unauthorized_client")
+}
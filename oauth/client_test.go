@@ -0,0 +1,60 @@
+package oauth
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/utils"
+)
+
+func TestRequestingClient(t *testing.T) {
+    setUpRepositories()
+    public := createClient(t, "Saturn", models.PublicClient)
+    confidential := createClient(t, "Mars", models.ConfidentialClient)
+
+    assert.Equal(t, public.ID, RequestingClient("", public.Key, true).ID, "should identify a public client by its client_id")
+    assert.Equal(t, uint(0), RequestingClient("", public.Key, false).ID, "should require authentication for other requests")
+    assert.Equal(t, uint(0), RequestingClient("", confidential.Key, true).ID, "should not identify a confidential client by its client_id")
+    authorization := utils.BasicAuthEncode(confidential.Key, "saturn-secret")
+    assert.Equal(t, confidential.ID, RequestingClient(authorization, "", false).ID, "should authenticate a confidential client")
+}
+
+func TestRefreshAndRevokeForPublicClients(t *testing.T) {
+    setUpRepositories()
+    public := createClient(t, "Saturn", models.PublicClient)
+    user, _ := createUser(t)
+
+    client := RequestingClient("", public.Key, true)
+    data := authorizationCodeData(user, client)
+    data["code_challenge"] = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+    data["code_challenge_method"] = S256CodeChallenge
+    grant, _ := AuthorizationCodeGrant(data)
+    tokens, err := AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+        "code_verifier": "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+    })
+    assert.Nil(t, err, "should exchange the code with the right verifier")
+
+    result, err := RefreshTokenRequest(utils.H{
+        "refresh_token": tokens["refresh_token"],
+        "scope": models.ReadScope,
+        "client": client,
+    })
+    assert.Nil(t, err, "should refresh the token of a public client")
+    _, err = RevocationRequest(utils.H{
+        "token": result["refresh_token"],
+        "token_type_hint": models.RefreshToken,
+        "client": client,
+    })
+    assert.Nil(t, err, "should revoke the token of a public client")
+    _, err = RefreshTokenRequest(utils.H{
+        "refresh_token": result["refresh_token"],
+        "scope": models.ReadScope,
+        "client": client,
+    })
+    assert.NotNil(t, err, "should not refresh a revoked token")
+}
@@ -23,4 +23,8 @@ const (
     // Response types
     Code                          string = "code"
     Token                         string = "token"
+
+    // Code challenge methods (RFC 7636)
+    PlainCodeChallenge            string = "plain"
+    S256CodeChallenge             string = "S256"
 )
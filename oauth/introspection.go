@@ -0,0 +1,67 @@
+package oauth
+
+import (
+    "github.com/earaujoassis/space/utils"
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/models"
+)
+
+// Token types which are looked up, in order, according to the `token_type_hint` (RFC 7662, section 2.1)
+func tokenTypesForHint(hint string) []string {
+    if hint == models.RefreshToken {
+        return []string{models.RefreshToken, models.AccessToken}
+    }
+    return []string{models.AccessToken, models.RefreshToken}
+}
+
+func IntrospectionRequest(data utils.H) (utils.H, error) {
+    var token string
+    var tokenTypeHint string
+
+    if data["token"] == nil || data["token"].(string) == "" {
+        return invalidRequestResult("")
+    }
+
+    token = data["token"].(string)
+
+    if data["token_type_hint"] != nil {
+        tokenTypeHint = data["token_type_hint"].(string)
+    }
+
+    // Unknown, expired or invalidated tokens are reported as inactive (RFC 7662, section 2.2)
+    if !security.ValidToken(token) && !security.ValidJWT(token) {
+        return utils.H{"active": false}, nil
+    }
+
+    for _, tokenType := range tokenTypesForHint(tokenTypeHint) {
+        var session models.Session
+        if tokenType == models.AccessToken {
+            session = FindAccessSession(token)
+        } else {
+            session = services.FindSessionByToken(token, tokenType)
+        }
+        if session.ID == 0 {
+            continue
+        }
+        result := utils.H{
+            "active": true,
+            "scope": session.Scopes,
+            "client_id": session.Client.Key,
+            "iat": session.Moment,
+        }
+        if session.ExpiresIn != 0 {
+            result["exp"] = session.Moment + session.ExpiresIn
+        }
+        if tokenType == models.AccessToken {
+            result["token_type"] = "Bearer"
+        }
+        if session.HasUser() {
+            result["sub"] = session.User.PublicId
+            result["username"] = session.User.Username
+        }
+        return result, nil
+    }
+
+    return utils.H{"active": false}, nil
+}
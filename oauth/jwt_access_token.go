@@ -0,0 +1,110 @@
+package oauth
+
+import (
+    "crypto"
+    "sync"
+    "time"
+
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/models"
+)
+
+const (
+    verificationKeysLifetime int64 = 300 // 5 min
+)
+
+var verificationKeysCache struct {
+    sync.Mutex
+    keys map[string]crypto.PublicKey
+    moment int64
+}
+
+// Public keys are cached, so self-contained tokens are validated without hitting the data store
+func verificationKeys() map[string]crypto.PublicKey {
+    verificationKeysCache.Lock()
+    defer verificationKeysCache.Unlock()
+
+    now := time.Now().UTC().Unix()
+    if verificationKeysCache.keys != nil && verificationKeysCache.moment + verificationKeysLifetime >= now {
+        return verificationKeysCache.keys
+    }
+    keys := make(map[string]crypto.PublicKey)
+    for _, storedKey := range services.PublishedSigningKeys() {
+        if key, err := storedKey.Signer(); err == nil {
+            keys[storedKey.Kid] = key.Public()
+        }
+    }
+    verificationKeysCache.keys = keys
+    verificationKeysCache.moment = now
+    return keys
+}
+
+// Clients may opt for self-contained access tokens (JWT); the `jti` claim holds the session UUID
+func accessTokenValue(session models.Session, client models.Client, issuer string) (string, error) {
+    if client.AccessTokenFormat != models.JWTAccessToken {
+        return session.Token, nil
+    }
+    key, kid, err := signingKey()
+    if err != nil {
+        return "", err
+    }
+    claims := map[string]interface{}{
+        "iss": issuer,
+        "sub": client.Key,
+        "client_id": client.Key,
+        "scope": session.Scopes,
+        "iat": session.Moment,
+        "exp": session.Moment + session.ExpiresIn,
+        "jti": session.UUID,
+    }
+    if session.HasUser() {
+        claims["sub"] = session.User.PublicId
+    }
+    return security.SignJWT(claims, key, kid)
+}
+
+func jwtAccessAuthentication(token string) models.Session {
+    claims, err := security.VerifyJWT(token, verificationKeys())
+    if err != nil {
+        return models.Session{}
+    }
+    jti, _ := claims["jti"].(string)
+    sub, _ := claims["sub"].(string)
+    clientKey, _ := claims["client_id"].(string)
+    scope, _ := claims["scope"].(string)
+    iat, _ := claims["iat"].(float64)
+    exp, _ := claims["exp"].(float64)
+    if jti == "" || models.AccessTokenRevoked(jti) {
+        return models.Session{}
+    }
+    session := models.Session{
+        UUID: jti,
+        Client: models.Client{Key: clientKey},
+        Moment: int64(iat),
+        ExpiresIn: int64(exp) - int64(iat),
+        TokenType: models.AccessToken,
+        Scopes: scope,
+    }
+    if sub != clientKey {
+        session.User = models.User{PublicId: sub}
+    }
+    return session
+}
+
+// Looks up the session for either an opaque or a self-contained access token, in the data store
+func FindAccessSession(token string) models.Session {
+    if security.ValidJWT(token) {
+        claims, err := security.VerifyJWT(token, verificationKeys())
+        if err != nil {
+            return models.Session{}
+        }
+        jti, _ := claims["jti"].(string)
+        session := services.FindSessionByUUID(jti)
+        if session.TokenType != models.AccessToken {
+            return models.Session{}
+        }
+        return session
+    }
+    return services.FindSessionByToken(token, models.AccessToken)
+}
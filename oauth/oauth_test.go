@@ -0,0 +1,57 @@
+package oauth
+
+import (
+    "os"
+    "testing"
+
+    "github.com/pquerna/otp"
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/repository"
+    "github.com/earaujoassis/space/services"
+)
+
+const (
+    testRedirectURI string = "https://saturn.com/callback"
+    testPassword string = "correct horse battery staple"
+)
+
+// Grant flows run against in-memory repositories and memory store; nothing else is required
+func setUpRepositories() {
+    os.Setenv("SPACE_STORAGE_SECRET", "Mx2kvQ9sT4bN7pLw3eRz8yUc5aHf6jDg")
+    memstore.Use(memstore.NewMemoryStore())
+    services.UseRepositories(repository.NewMemoryRepositories())
+}
+
+func createClient(t *testing.T, name, clientType string) models.Client {
+    client := services.CreateNewClient(name,
+        "Testing client",
+        "saturn-secret",
+        models.ReadWriteScope,
+        "https://saturn.com",
+        testRedirectURI,
+        clientType,
+        models.OpaqueAccessToken)
+    assert.NotEqual(t, uint(0), client.ID, "should create a client")
+    return client
+}
+
+func createUser(t *testing.T) (models.User, *otp.Key) {
+    user := models.User{
+        FirstName: "Jane",
+        LastName: "Doe",
+        Username: "janedoe",
+        Email: "jane@saturn.com",
+        Passphrase: testPassword,
+        Active: true,
+        Client: services.FindOrCreateClient("Jupiter"),
+        Language: models.Language{Name: "English", IsoCode: "en-US"},
+    }
+    codeSecretKey := user.GenerateCodeSecret()
+    _, err := user.GenerateRecoverSecret()
+    assert.Nil(t, err, "should generate a recover secret")
+    assert.Nil(t, services.CreateUser(&user), "should create a user")
+    return user, codeSecretKey
+}
@@ -0,0 +1,100 @@
+package oauth
+
+import (
+    "crypto"
+    "errors"
+    "strings"
+    "time"
+
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/models"
+)
+
+const (
+    idTokenExpirationLength int64 = 3600 // 60 min
+)
+
+// Tokens are signed with the active key from the key store (see `space keys rotate`)
+func signingKey() (crypto.Signer, string, error) {
+    activeKey := services.ActiveSigningKey()
+    if activeKey.ID == 0 {
+        return nil, "", errors.New("There's no active signing key")
+    }
+    key, err := activeKey.Signer()
+    if err != nil {
+        return nil, "", err
+    }
+    return key, activeKey.Kid, nil
+}
+
+func PublicKeys() []security.JSONWebKey {
+    var keys []security.JSONWebKey = make([]security.JSONWebKey, 0)
+
+    for _, storedKey := range services.PublishedSigningKeys() {
+        key, err := storedKey.Signer()
+        if err != nil {
+            continue
+        }
+        if jwk, err := security.PublicJSONWebKey(key.Public(), storedKey.Kid); err == nil {
+            keys = append(keys, jwk)
+        }
+    }
+    return keys
+}
+
+func SigningAlgorithms() []string {
+    var algorithms []string = make([]string, 0)
+
+    for _, jwk := range PublicKeys() {
+        if !containsString(algorithms, jwk.Alg) {
+            algorithms = append(algorithms, jwk.Alg)
+        }
+    }
+    return algorithms
+}
+
+func containsString(values []string, value string) bool {
+    for _, current := range values {
+        if current == value {
+            return true
+        }
+    }
+    return false
+}
+
+// Splits the requested scope into a Space scope and the OpenID Connect flag;
+// unknown scopes fall back to the public scope
+func ParseScope(requested string) (string, bool) {
+    var scope string = models.PublicScope
+    var openID bool
+
+    for _, value := range strings.Fields(requested) {
+        switch value {
+        case models.OpenIDScope:
+            openID = true
+        case models.PublicScope, models.ReadScope, models.ReadWriteScope:
+            scope = value
+        }
+    }
+    return scope, openID
+}
+
+func createIDToken(user models.User, client models.Client, nonce, issuer string) (string, error) {
+    key, kid, err := signingKey()
+    if err != nil {
+        return "", err
+    }
+    now := time.Now().UTC().Unix()
+    claims := map[string]interface{}{
+        "iss": issuer,
+        "sub": user.PublicId,
+        "aud": client.Key,
+        "iat": now,
+        "exp": now + idTokenExpirationLength,
+    }
+    if nonce != "" {
+        claims["nonce"] = nonce
+    }
+    return security.SignJWT(claims, key, kid)
+}
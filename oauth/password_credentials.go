@@ -0,0 +1,115 @@
+package oauth
+
+import (
+    "strings"
+    "time"
+
+    "github.com/earaujoassis/space/utils"
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/services/logger"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/policy"
+    "github.com/earaujoassis/space/models"
+)
+
+func PasswordCredentialsRequest(data utils.H) (utils.H, error) {
+    var user models.User
+    var client models.Client
+
+    var username string
+    var password string
+    var passcode string
+    var scope string
+    var issuer string
+
+    var ip string
+    var userAgent string
+
+    if data["username"] == nil || data["password"] == nil || data["passcode"] == nil || data["client"] == nil {
+        return invalidRequestResult("")
+    }
+
+    if data["ip"] != nil {
+        ip = data["ip"].(string)
+    }
+
+    if data["userAgent"] != nil {
+        userAgent = data["userAgent"].(string)
+    }
+
+    if data["scope"] != nil {
+        scope = data["scope"].(string)
+    }
+
+    if data["issuer"] != nil {
+        issuer = data["issuer"].(string)
+    }
+
+    username = data["username"].(string)
+    password = data["password"].(string)
+    passcode = data["passcode"].(string)
+    client = data["client"].(models.Client)
+
+    if !security.ValidEmail(username) && !security.ValidRandomString(username) {
+        return invalidRequestResult("")
+    }
+
+    if scope == "" {
+        scope = models.PublicScope
+    }
+    if scope != models.PublicScope && scope != models.ReadScope && scope != models.ReadWriteScope {
+        return invalidScopeResult("")
+    }
+    if scope != models.PublicScope && !strings.Contains(client.Scopes, scope) {
+        return invalidScopeResult("")
+    }
+
+    var userID string = ip
+    var statusSignInAttempts = policy.SignInAttemptStatus(ip)
+    user = services.FindUserByAccountHolder(username)
+    if user.ID == 0 || statusSignInAttempts == policy.Blocked {
+        policy.RegisterSignInAttempt(userID)
+        return invalidGrantResult("")
+    }
+    userID = user.UUID
+    statusSignInAttempts = policy.SignInAttemptStatus(userID)
+    if statusSignInAttempts == policy.Blocked || !user.Active || services.SecurityKeyRequired(user) ||
+            !services.AuthenticPassword(&user, password) || !services.AuthenticPasscode(user, passcode) {
+        policy.RegisterSignInAttempt(userID)
+        return invalidGrantResult("")
+    }
+
+    refreshToken := services.CreateSession(user,
+        client,
+        ip,
+        userAgent,
+        scope,
+        models.RefreshToken)
+    accessToken := services.CreateChildSession(refreshToken, models.AccessToken)
+
+    if accessToken.ID == 0 || refreshToken.ID == 0 {
+        return serverErrorResult("")
+    }
+    accessTokenString, err := accessTokenValue(accessToken, client, issuer)
+    if err != nil {
+        return serverErrorResult("")
+    }
+
+    go logger.LogAction("session.created", utils.H{
+        "Email": user.Email,
+        "FirstName": user.FirstName,
+        "Ip": accessToken.Ip,
+        "CreatedAt": accessToken.CreatedAt.Format(time.RFC850),
+    })
+    policy.RegisterSuccessfulSignIn(user.UUID)
+    policy.RegisterSuccessfulSignIn(ip)
+
+    return utils.H{
+        "user_id": user.PublicId,
+        "access_token": accessTokenString,
+        "token_type": "Bearer",
+        "expires_in": accessToken.ExpiresIn,
+        "refresh_token": refreshToken.Token,
+        "scope": scope,
+    }, nil
+}
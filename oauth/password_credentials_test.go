@@ -0,0 +1,45 @@
+package oauth
+
+import (
+    "testing"
+    "time"
+
+    "github.com/pquerna/otp/totp"
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/utils"
+)
+
+func TestPasswordCredentialsRequest(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+    user, codeSecretKey := createUser(t)
+    passcode, _ := totp.GenerateCode(codeSecretKey.Secret(), time.Now().UTC())
+
+    data := utils.H{
+        "username": user.Email,
+        "password": "wrong horse battery staple",
+        "passcode": passcode,
+        "client": client,
+        "scope": models.ReadScope,
+        "ip": "127.0.0.1",
+        "userAgent": "Testing",
+    }
+    result, err := PasswordCredentialsRequest(data)
+    assert.NotNil(t, err, "should not issue tokens for a wrong password")
+    assert.Equal(t, InvalidGrant, result["error"], "should return invalid_grant")
+
+    data["password"] = testPassword
+    result, err = PasswordCredentialsRequest(data)
+    assert.Nil(t, err, "should issue tokens for the right credentials")
+    assert.Equal(t, user.PublicId, result["user_id"], "should return the user")
+    assert.NotEmpty(t, result["refresh_token"], "should issue a refresh token")
+    session := AccessAuthentication(result["access_token"].(string))
+    assert.Equal(t, user.ID, session.User.ID, "should issue the access token to the user")
+
+    data["username"] = "unknown@saturn.com"
+    result, err = PasswordCredentialsRequest(data)
+    assert.NotNil(t, err, "should not issue tokens for an unknown user")
+    assert.Equal(t, InvalidGrant, result["error"], "should return invalid_grant")
+}
@@ -0,0 +1,32 @@
+package oauth
+
+import (
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+
+    "github.com/earaujoassis/space/security"
+)
+
+func validCodeChallengeMethod(method string) bool {
+    return method == PlainCodeChallenge || method == S256CodeChallenge
+}
+
+// Checks a code verifier against the code challenge recorded for the grant (RFC 7636, section 4.6)
+func validCodeVerifier(verifier, challenge, method string) bool {
+    var computed string
+
+    if !security.ValidCodeVerifier(verifier) {
+        return false
+    }
+    switch method {
+    case S256CodeChallenge:
+        digest := sha256.Sum256([]byte(verifier))
+        computed = base64.RawURLEncoding.EncodeToString(digest[:])
+    case PlainCodeChallenge:
+        computed = verifier
+    default:
+        return false
+    }
+    return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
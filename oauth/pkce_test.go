@@ -0,0 +1,25 @@
+package oauth
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestValidCodeVerifier(t *testing.T) {
+    // Example from RFC 7636, appendix B
+    verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+    challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+    assert.True(t, validCodeVerifier(verifier, challenge, S256CodeChallenge), "should validate S256 code verifier")
+    assert.False(t, validCodeVerifier(verifier, verifier, S256CodeChallenge), "should invalidate S256 code verifier against a plain challenge")
+    assert.True(t, validCodeVerifier(verifier, verifier, PlainCodeChallenge), "should validate plain code verifier")
+    assert.False(t, validCodeVerifier(verifier, challenge, PlainCodeChallenge), "should invalidate plain code verifier")
+    assert.False(t, validCodeVerifier(verifier, challenge, "S512"), "should invalidate unknown challenge method")
+    assert.False(t, validCodeVerifier("short", "short", PlainCodeChallenge), "should invalidate malformed code verifier")
+}
+
+func TestValidCodeChallengeMethod(t *testing.T) {
+    assert.True(t, validCodeChallengeMethod(S256CodeChallenge), "should accept S256")
+    assert.True(t, validCodeChallengeMethod(PlainCodeChallenge), "should accept plain")
+    assert.False(t, validCodeChallengeMethod(""), "should not accept an empty method")
+}
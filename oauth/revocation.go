@@ -0,0 +1,50 @@
+package oauth
+
+import (
+    "github.com/earaujoassis/space/utils"
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/models"
+)
+
+func RevocationRequest(data utils.H) (utils.H, error) {
+    var client models.Client
+
+    var token string
+    var tokenTypeHint string
+
+    if data["token"] == nil || data["token"].(string) == "" || data["client"] == nil {
+        return invalidRequestResult("")
+    }
+
+    token = data["token"].(string)
+    client = data["client"].(models.Client)
+
+    if data["token_type_hint"] != nil {
+        tokenTypeHint = data["token_type_hint"].(string)
+    }
+
+    // Invalid tokens do not cause an error response (RFC 7009, section 2.2)
+    if !security.ValidToken(token) && !security.ValidJWT(token) {
+        return utils.H{}, nil
+    }
+
+    for _, tokenType := range tokenTypesForHint(tokenTypeHint) {
+        var session models.Session
+        if tokenType == models.AccessToken {
+            session = FindAccessSession(token)
+        } else {
+            session = services.FindSessionByToken(token, tokenType)
+        }
+        if session.ID == 0 {
+            continue
+        }
+        if session.Client.ID != client.ID {
+            return unauthorizedClientResult("")
+        }
+        services.RevokeSession(session)
+        break
+    }
+
+    return utils.H{}, nil
+}
@@ -0,0 +1,42 @@
+package oauth
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/utils"
+)
+
+func TestRevocationRequest(t *testing.T) {
+    setUpRepositories()
+    client := createClient(t, "Saturn", models.ConfidentialClient)
+    anotherClient := createClient(t, "Mars", models.ConfidentialClient)
+    user, _ := createUser(t)
+
+    grant, _ := AuthorizationCodeGrant(authorizationCodeData(user, client))
+    tokens, _ := AccessTokenRequest(utils.H{
+        "code": grant["code"],
+        "redirect_uri": testRedirectURI,
+        "client": client,
+    })
+    accessToken := tokens["access_token"].(string)
+    accessSession := AccessAuthentication(accessToken)
+
+    result, err := RevocationRequest(utils.H{"token": tokens["refresh_token"], "client": anotherClient})
+    assert.NotNil(t, err, "should not revoke tokens issued to another client")
+    assert.Equal(t, UnauthorizedClient, result["error"], "should return unauthorized_client")
+
+    _, err = RevocationRequest(utils.H{
+        "token": tokens["refresh_token"],
+        "token_type_hint": models.RefreshToken,
+        "client": client,
+    })
+    assert.Nil(t, err, "should revoke the refresh token")
+    assert.Equal(t, uint(0), AccessAuthentication(accessToken).ID, "should revoke the access token issued with it")
+    assert.True(t, models.AccessTokenRevoked(accessSession.UUID), "should add the access token to the revocation list")
+
+    _, err = RevocationRequest(utils.H{"token": "unknown", "client": client})
+    assert.Nil(t, err, "should not fail for invalid tokens")
+}
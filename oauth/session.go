@@ -3,8 +3,12 @@ package oauth
 import (
     "github.com/earaujoassis/space/models"
     "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
 )
 
 func AccessAuthentication(token string) models.Session {
+    if security.ValidJWT(token) {
+        return jwtAccessAuthentication(token)
+    }
     return services.FindSessionByToken(token, models.AccessToken)
 }
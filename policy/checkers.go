@@ -1,19 +1,20 @@
 package policy
 
 import (
-    "github.com/garyburd/redigo/redis"
+    "fmt"
+    "strconv"
+
     "github.com/earaujoassis/space/memstore"
 )
 
-func SignInAttemptStatus(id string) string {
-    memstore.Start()
-    defer memstore.Close()
-
-    if blockExists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-in.blocked", id)); blockExists {
+// Attempts for an action are kept at `<action>.attempt` and blocks at `<action>.blocked`
+func attemptStatus(action, id string) string {
+    if blockExists, _ := memstore.HExists(fmt.Sprintf("%s.blocked", action), id); blockExists {
         return Blocked
     }
-    if attemptExists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-in.attempt", id)); attemptExists {
-        reply, _ := redis.Int(memstore.Do("HGET", "sign-in.attempt", id))
+    if attemptExists, _ := memstore.HExists(fmt.Sprintf("%s.attempt", action), id); attemptExists {
+        value, _ := memstore.HGet(fmt.Sprintf("%s.attempt", action), id)
+        reply, _ := strconv.Atoi(value)
         switch {
         case reply > 0 && reply <= attemptsUntilPreblock:
             return Clear
@@ -26,23 +27,26 @@ func SignInAttemptStatus(id string) string {
     return Clear
 }
 
+func SignInAttemptStatus(id string) string {
+    return attemptStatus(signInAction, id)
+}
+
 func SignUpAttemptStatus(id string) string {
-    memstore.Start()
-    defer memstore.Close()
+    return attemptStatus(signUpAction, id)
+}
 
-    if blockExists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-up.blocked", id)); blockExists {
-        return Blocked
-    }
-    if attemptExists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-up.attempt", id)); attemptExists {
-        reply, _ := redis.Int(memstore.Do("HGET", "sign-up.attempt", id))
-        switch {
-        case reply > 0 && reply <= attemptsUntilPreblock:
-            return Clear
-        case reply > attemptsUntilPreblock && reply <= attemptsUntilBlock:
-            return Preblocked
-        case reply > attemptsUntilBlock:
-            return Blocked
-        }
-    }
-    return Clear
+func ConfirmationAttemptStatus(id string) string {
+    return attemptStatus(confirmationAction, id)
+}
+
+func RecoveryAttemptStatus(id string) string {
+    return attemptStatus(recoveryAction, id)
+}
+
+func PasswordResetAttemptStatus(id string) string {
+    return attemptStatus(passwordResetAction, id)
+}
+
+func SecurityKeyOptionsAttemptStatus(id string) string {
+    return attemptStatus(securityKeyOptionsAction, id)
 }
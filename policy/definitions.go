@@ -8,6 +8,17 @@ const (
     Blocked                       string = "blocked"
     Clear                         string = "clear"
 
+    signInAction                  string = "sign-in"
+    signUpAction                  string = "sign-up"
+    confirmationAction            string = "confirmation"
+    recoveryAction                string = "recovery"
+    passwordResetAction           string = "password-reset"
+    securityKeyOptionsAction      string = "security-key-options"
+
     blockPeriodFailedSignIn        int64 = 43200 // 12 hours
     blockPeriodFailedSignUp        int64 = 720   // 12 minutes
+    blockPeriodConfirmation        int64 = 3600  // 60 minutes
+    blockPeriodFailedRecovery      int64 = 43200 // 12 hours
+    blockPeriodPasswordReset       int64 = 3600  // 60 minutes
+    blockPeriodSecurityKeyOptions  int64 = 720   // 12 minutes
 )
@@ -0,0 +1,123 @@
+package password
+
+import (
+    "bytes"
+    "crypto/sha1"
+    "encoding/hex"
+    "errors"
+    "io"
+    "os"
+    "strings"
+)
+
+const (
+    hashLength          int = 40
+    maximumLineLength   int = 128
+)
+
+// Breached passwords are looked up in the Pwned Passwords list ordered by hash (SHA-1 digests,
+// one `<digest>:<count>` per line); the file is never loaded, it's binary searched in place
+type BreachedList struct {
+    reader      io.ReaderAt
+    size        int64
+}
+
+// The list must be sorted by digest; only its first line is validated
+func NewBreachedList(reader io.ReaderAt, size int64) (*BreachedList, error) {
+    list := &BreachedList{reader: reader, size: size}
+    line, _, _, err := list.lineAt(0)
+    if err != nil {
+        return nil, err
+    }
+    if line != "" {
+        digest := lineDigest(line)
+        if _, err := hex.DecodeString(digest); err != nil || len(digest) != hashLength {
+            return nil, errors.New("Invalid breached password digest")
+        }
+    }
+    return list, nil
+}
+
+// The file is kept open while the application runs
+func LoadBreachedList(path string) (*BreachedList, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    info, err := file.Stat()
+    if err != nil {
+        file.Close()
+        return nil, err
+    }
+    list, err := NewBreachedList(file, info.Size())
+    if err != nil {
+        file.Close()
+        return nil, err
+    }
+    return list, nil
+}
+
+func lineDigest(line string) string {
+    return strings.ToUpper(strings.TrimSpace(strings.SplitN(line, ":", 2)[0]))
+}
+
+// Returns the first line starting at or after offset, its start and the start of the next line;
+// the line is empty once offset reaches the end of the list
+func (list *BreachedList) lineAt(offset int64) (string, int64, int64, error) {
+    start := offset
+    if offset > 0 {
+        // Reading from the previous byte tells whether offset is already at the start of a line
+        start = offset - 1
+    }
+    if start >= list.size {
+        return "", list.size, list.size, nil
+    }
+    buffer := make([]byte, 2 * maximumLineLength)
+    read, err := list.reader.ReadAt(buffer, start)
+    if err != nil && err != io.EOF {
+        return "", 0, 0, err
+    }
+    buffer = buffer[:read]
+    if offset > 0 {
+        newline := bytes.IndexByte(buffer, '\n')
+        if newline < 0 {
+            if start + int64(read) >= list.size {
+                return "", list.size, list.size, nil
+            }
+            return "", 0, 0, errors.New("Invalid breached password list")
+        }
+        buffer = buffer[newline + 1:]
+        start += int64(newline + 1)
+    }
+    end := bytes.IndexByte(buffer, '\n')
+    if end < 0 {
+        if start + int64(len(buffer)) < list.size {
+            return "", 0, 0, errors.New("Invalid breached password list")
+        }
+        end = len(buffer)
+        return string(buffer[:end]), start, start + int64(end), nil
+    }
+    return string(buffer[:end]), start, start + int64(end + 1), nil
+}
+
+// Lines starting before low are always lower than the digest; lines starting at or after high
+// are never lower than it. Each lookup reads about log2(size) lines
+func (list *BreachedList) Contains(passphrase string) bool {
+    sum := sha1.Sum([]byte(passphrase))
+    digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+    low, high := int64(0), list.size
+    for low < high {
+        middle := low + (high - low) / 2
+        line, start, next, err := list.lineAt(middle)
+        if err != nil {
+            return false
+        }
+        if line == "" || start >= high || lineDigest(line) >= digest {
+            high = middle
+        } else {
+            low = next
+        }
+    }
+    line, _, _, err := list.lineAt(low)
+    return err == nil && lineDigest(line) == digest
+}
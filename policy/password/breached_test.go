@@ -0,0 +1,72 @@
+package password
+
+import (
+    "crypto/sha1"
+    "encoding/hex"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sort"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// SHA-1 digests of "correct horse battery staple" and "password123", sorted
+const breachedSample = "ABF7AAD6438836DBE526AA231ABDE2D0EEF74D42:3\r\n" +
+    "CBFDAC6008F9CAB4083784CBD1874F76618D2A97:2412345\r\n"
+
+func breachedDigest(passphrase string) string {
+    sum := sha1.Sum([]byte(passphrase))
+    return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestBreachedList(t *testing.T) {
+    sample := strings.NewReader(breachedSample)
+    list, err := NewBreachedList(sample, sample.Size())
+    assert.Nil(t, err, "should have opened the breached list")
+    assert.True(t, list.Contains("password123"), "should find a breached password")
+    assert.True(t, list.Contains("correct horse battery staple"), "should find the first digest")
+    assert.False(t, list.Contains("Password123"), "should not find a password out of the list")
+
+    policy := &Policy{MinimumLength: 8, CharacterClasses: 1, BreachedList: list}
+    assert.Equal(t, []string{Breached}, policy.Validate("password123"), "should refuse a breached password")
+    assert.Empty(t, policy.Validate("unbreached passphrase"), "should accept other passwords")
+
+    empty := strings.NewReader("")
+    list, err = NewBreachedList(empty, empty.Size())
+    assert.Nil(t, err, "should accept an empty list")
+    assert.False(t, list.Contains("password123"), "should not find a password in an empty list")
+}
+
+func TestBreachedListSearch(t *testing.T) {
+    var lines []string
+
+    for i := 0; i < 1000; i++ {
+        lines = append(lines, fmt.Sprintf("%s:%d", breachedDigest(fmt.Sprintf("breached-%d", i)), i * 37))
+    }
+    sort.Strings(lines)
+    file, err := ioutil.TempFile("", "breached")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.Remove(file.Name())
+    file.WriteString(strings.Join(lines, "\n"))
+    file.Close()
+
+    list, err := LoadBreachedList(file.Name())
+    assert.Nil(t, err, "should have opened the breached list")
+    for i := 0; i < 1000; i++ {
+        assert.True(t, list.Contains(fmt.Sprintf("breached-%d", i)), "should find every breached password")
+        assert.False(t, list.Contains(fmt.Sprintf("unbreached-%d", i)), "should not find passwords out of the list")
+    }
+}
+
+func TestInvalidBreachedList(t *testing.T) {
+    invalid := strings.NewReader("not a digest\n")
+    _, err := NewBreachedList(invalid, invalid.Size())
+    assert.NotNil(t, err, "should refuse invalid digests")
+    _, err = LoadBreachedList("/nonexistent/breached.txt")
+    assert.NotNil(t, err, "should fail for a missing file")
+}
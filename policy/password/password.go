@@ -0,0 +1,136 @@
+package password
+
+import (
+    "strconv"
+    "strings"
+    "sync"
+    "unicode"
+
+    "github.com/earaujoassis/space/config"
+)
+
+const (
+    defaultMinimumLength        int = 10
+    defaultCharacterClasses     int = 1
+    personalDataMinimumLength   int = 3
+
+    // Machine-readable rejection reasons
+    TooShort                    string = "too_short"
+    MissingCharacterClasses     string = "missing_character_classes"
+    PersonalData                string = "personal_data"
+    Breached                    string = "breached"
+)
+
+// Passwords must have a minimum length and a minimum number of character classes (lowercase,
+// uppercase, digits and symbols); they can't contain personal data nor be a breached password
+type Policy struct {
+    MinimumLength int
+    CharacterClasses int
+    BreachedList *BreachedList
+}
+
+var current *Policy
+var currentErr error
+var currentOnce sync.Once
+
+func configuredInt(key string, fallback int) int {
+    if value, err := strconv.Atoi(config.GetConfig(key)); err == nil && value > 0 {
+        return value
+    }
+    return fallback
+}
+
+func load() {
+    currentOnce.Do(func() {
+        policy := &Policy{
+            MinimumLength: configuredInt("SPACE_PASSWORD_MIN_LENGTH", defaultMinimumLength),
+            CharacterClasses: configuredInt("SPACE_PASSWORD_CHARACTER_CLASSES", defaultCharacterClasses),
+        }
+        if path := config.GetConfig("SPACE_BREACHED_PASSWORDS_FILE"); path != "" {
+            list, err := LoadBreachedList(path)
+            if err != nil {
+                currentErr = err
+                return
+            }
+            policy.BreachedList = list
+        }
+        current = policy
+    })
+}
+
+// The policy is configured through SPACE_PASSWORD_MIN_LENGTH, SPACE_PASSWORD_CHARACTER_CLASSES
+// and SPACE_BREACHED_PASSWORDS_FILE; it's loaded once, when the application starts
+func Load() error {
+    load()
+    return currentErr
+}
+
+// A policy which failed to load is never replaced by a weaker one: every call panics
+func Current() *Policy {
+    load()
+    if currentErr != nil {
+        panic(currentErr)
+    }
+    return current
+}
+
+// Validates a password with the current policy
+func Validate(passphrase string, personalData ...string) []string {
+    return Current().Validate(passphrase, personalData...)
+}
+
+func characterClasses(passphrase string) int {
+    var lower, upper, digit, symbol int
+
+    for _, character := range passphrase {
+        switch {
+        case unicode.IsLower(character):
+            lower = 1
+        case unicode.IsUpper(character):
+            upper = 1
+        case unicode.IsDigit(character):
+            digit = 1
+        default:
+            symbol = 1
+        }
+    }
+    return lower + upper + digit + symbol
+}
+
+// Personal data (names, username and email) can't be part of the password, case-insensitively;
+// emails are also checked by their local part
+func containsPersonalData(passphrase string, personalData []string) bool {
+    lowerPassphrase := strings.ToLower(passphrase)
+    for _, data := range personalData {
+        candidates := []string{data}
+        if at := strings.LastIndex(data, "@"); at > 0 {
+            candidates = append(candidates, data[:at])
+        }
+        for _, candidate := range candidates {
+            candidate = strings.ToLower(strings.TrimSpace(candidate))
+            if len(candidate) >= personalDataMinimumLength && strings.Contains(lowerPassphrase, candidate) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Returns the reasons a password is rejected; it's accepted when there's none
+func (policy *Policy) Validate(passphrase string, personalData ...string) []string {
+    reasons := make([]string, 0)
+
+    if len([]rune(passphrase)) < policy.MinimumLength {
+        reasons = append(reasons, TooShort)
+    }
+    if characterClasses(passphrase) < policy.CharacterClasses {
+        reasons = append(reasons, MissingCharacterClasses)
+    }
+    if containsPersonalData(passphrase, personalData) {
+        reasons = append(reasons, PersonalData)
+    }
+    if policy.BreachedList != nil && policy.BreachedList.Contains(passphrase) {
+        reasons = append(reasons, Breached)
+    }
+    return reasons
+}
@@ -0,0 +1,40 @@
+package password
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestPolicyLength(t *testing.T) {
+    policy := &Policy{MinimumLength: 10, CharacterClasses: 1}
+    assert.Equal(t, []string{TooShort}, policy.Validate("short"), "should refuse a short password")
+    assert.Empty(t, policy.Validate("longenoughpassword"), "should accept a long password")
+}
+
+func TestPolicyCharacterClasses(t *testing.T) {
+    policy := &Policy{MinimumLength: 8, CharacterClasses: 3}
+    assert.Equal(t, []string{MissingCharacterClasses}, policy.Validate("lowercaseonly"),
+        "should refuse a password with a single character class")
+    assert.Empty(t, policy.Validate("Lowercase123"), "should accept three character classes")
+    assert.Empty(t, policy.Validate("lowercase-123"), "should count symbols as a character class")
+}
+
+func TestPolicyPersonalData(t *testing.T) {
+    policy := &Policy{MinimumLength: 8, CharacterClasses: 1}
+    personalData := []string{"Ewerton", "Assis", "earaujoassis", "ewerton@example.com"}
+    assert.Equal(t, []string{PersonalData}, policy.Validate("myEARAUJOASSIS2017", personalData...),
+        "should refuse the username, case-insensitively")
+    assert.Equal(t, []string{PersonalData}, policy.Validate("ewerton-rocks", personalData...),
+        "should refuse the first name")
+    assert.Empty(t, policy.Validate("unrelated passphrase", personalData...),
+        "should accept a password without personal data")
+    assert.Empty(t, policy.Validate("unrelated passphrase", "", "ab"),
+        "should ignore empty or very short personal data")
+}
+
+func TestPolicyReasons(t *testing.T) {
+    policy := &Policy{MinimumLength: 10, CharacterClasses: 2}
+    assert.Equal(t, []string{TooShort, MissingCharacterClasses, PersonalData},
+        policy.Validate("space", "space"), "should return every rejection reason")
+}
@@ -1,72 +1,85 @@
 package policy
 
 import (
+    "fmt"
+    "strconv"
     "time"
 
-    "github.com/garyburd/redigo/redis"
     "github.com/earaujoassis/space/memstore"
 )
 
-func RegisterSignInAttempt(id string) {
-    memstore.Start()
-    defer memstore.Close()
-
+func registerAttempt(action, id string, blockPeriod int64) {
+    attemptKey := fmt.Sprintf("%s.attempt", action)
+    blockKey := fmt.Sprintf("%s.blocked", action)
     nowMoment := time.Now().UTC().Unix()
-    if blockExists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-in.blocked", id)); blockExists {
-        blockReply, _ := redis.Int64(memstore.Do("HGET", "sign-in.blocked", id))
-        if (nowMoment - blockReply) >= blockPeriodFailedSignIn  {
-            memstore.Do("HDEL", "sign-in.blocked", id)
-            memstore.Do("HSET", "sign-in.attempt", id, 1)
+    if blockExists, _ := memstore.HExists(blockKey, id); blockExists {
+        blockValue, _ := memstore.HGet(blockKey, id)
+        blockReply, _ := strconv.ParseInt(blockValue, 10, 64)
+        if (nowMoment - blockReply) >= blockPeriod {
+            memstore.HDel(blockKey, id)
+            memstore.HSet(attemptKey, id, "1")
         }
         return
     }
-    if exists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-in.attempt", id)); !exists {
-        memstore.Do("HSET", "sign-in.attempt", id, 1)
+    if exists, _ := memstore.HExists(attemptKey, id); !exists {
+        memstore.HSet(attemptKey, id, "1")
     } else {
-        memstore.Do("HINCRBY", "sign-in.attempt", id, 1)
-        reply, _ := redis.Int(memstore.Do("HGET", "sign-in.attempt", id))
-        if reply >= attemptsUntilBlock {
-            memstore.Do("HSET", "sign-in.blocked", id, nowMoment)
+        reply, _ := memstore.HIncrBy(attemptKey, id, 1)
+        if reply >= int64(attemptsUntilBlock) {
+            memstore.HSet(blockKey, id, strconv.FormatInt(nowMoment, 10))
         }
     }
 }
 
-func RegisterSuccessfulSignIn(id string) {
-    memstore.Start()
-    defer memstore.Close()
+func clearAttempts(action, id string) {
+    memstore.HDel(fmt.Sprintf("%s.attempt", action), id)
+    memstore.HDel(fmt.Sprintf("%s.blocked", action), id)
+}
 
-    memstore.Do("HDEL", "sign-in.attempt", id)
-    memstore.Do("HDEL", "sign-in.blocked", id)
+func RegisterSignInAttempt(id string) {
+    registerAttempt(signInAction, id, blockPeriodFailedSignIn)
 }
 
-func RegisterSignUpAttempt(id string) {
-    memstore.Start()
-    defer memstore.Close()
+func RegisterSuccessfulSignIn(id string) {
+    clearAttempts(signInAction, id)
+}
 
-    nowMoment := time.Now().UTC().Unix()
-    if blockExists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-up.blocked", id)); blockExists {
-        blockReply, _ := redis.Int64(memstore.Do("HGET", "sign-up.blocked", id))
-        if (nowMoment - blockReply) >= blockPeriodFailedSignUp {
-            memstore.Do("HDEL", "sign-up.blocked", id)
-            memstore.Do("HSET", "sign-up.attempt", id, 1)
-        }
-        return
-    }
-    if exists, _ := redis.Bool(memstore.Do("HEXISTS", "sign-up.attempt", id)); !exists {
-        memstore.Do("HSET", "sign-up.attempt", id, 1)
-    } else {
-        memstore.Do("HINCRBY", "sign-up.attempt", id, 1)
-        reply, _ := redis.Int(memstore.Do("HGET", "sign-up.attempt", id))
-        if reply >= attemptsUntilBlock {
-            memstore.Do("HSET", "sign-up.blocked", id, nowMoment)
-        }
-    }
+func RegisterSignUpAttempt(id string) {
+    registerAttempt(signUpAction, id, blockPeriodFailedSignUp)
 }
 
 func RegisterSuccessfulSignUp(id string) {
-    memstore.Start()
-    defer memstore.Close()
+    clearAttempts(signUpAction, id)
+}
+
+func RegisterConfirmationAttempt(id string) {
+    registerAttempt(confirmationAction, id, blockPeriodConfirmation)
+}
+
+func RegisterSuccessfulConfirmation(id string) {
+    clearAttempts(confirmationAction, id)
+}
+
+func RegisterRecoveryAttempt(id string) {
+    registerAttempt(recoveryAction, id, blockPeriodFailedRecovery)
+}
+
+func RegisterSuccessfulRecovery(id string) {
+    clearAttempts(recoveryAction, id)
+}
+
+func RegisterPasswordResetAttempt(id string) {
+    registerAttempt(passwordResetAction, id, blockPeriodPasswordReset)
+}
+
+func RegisterSuccessfulPasswordReset(id string) {
+    clearAttempts(passwordResetAction, id)
+}
+
+func RegisterSecurityKeyOptionsAttempt(id string) {
+    registerAttempt(securityKeyOptionsAction, id, blockPeriodSecurityKeyOptions)
+}
 
-    memstore.Do("HDEL", "sign-up.attempt", id)
-    memstore.Do("HDEL", "sign-up.blocked", id)
+func RegisterSuccessfulSecurityKeyOptions(id string) {
+    clearAttempts(securityKeyOptionsAction, id)
 }
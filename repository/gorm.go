@@ -0,0 +1,242 @@
+package repository
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/jinzhu/gorm"
+
+    "github.com/earaujoassis/space/models"
+)
+
+type gormUserRepository struct {
+    db *gorm.DB
+}
+
+type gormClientRepository struct {
+    db *gorm.DB
+}
+
+type gormSessionRepository struct {
+    db *gorm.DB
+}
+
+// Repositories backed by the relational data store (see datastore.GetDataStoreConnection)
+func NewGormRepositories(db *gorm.DB) Repositories {
+    return Repositories{
+        Users: &gormUserRepository{db: db},
+        Clients: &gormClientRepository{db: db},
+        Sessions: &gormSessionRepository{db: db},
+    }
+}
+
+func (repository *gormUserRepository) Create(user *models.User) error {
+    result := repository.db.Create(user)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected < 1 {
+        return fmt.Errorf("%v", result.GetErrors())
+    }
+    return nil
+}
+
+func (repository *gormUserRepository) Save(user *models.User) error {
+    return repository.db.Set("gorm:save_associations", false).Save(user).Error
+}
+
+func (repository *gormUserRepository) SavePassphrase(user models.User) error {
+    return repository.db.Model(&user).UpdateColumn("passphrase", user.Passphrase).Error
+}
+
+func (repository *gormUserRepository) findWhere(query string, args ...interface{}) models.User {
+    var user models.User
+    repository.db.Preload("Client").Preload("Language").Where(query, args...).First(&user)
+    return user
+}
+
+func (repository *gormUserRepository) FindByID(id uint) models.User {
+    return repository.findWhere("id = ?", id)
+}
+
+func (repository *gormUserRepository) FindByUUID(uuid string) models.User {
+    return repository.findWhere("uuid = ?", uuid)
+}
+
+func (repository *gormUserRepository) FindByPublicId(publicId string) models.User {
+    return repository.findWhere("public_id = ?", publicId)
+}
+
+func (repository *gormUserRepository) FindByAccountHolder(holder string) models.User {
+    return repository.findWhere("username = ? OR email = ?", holder, holder)
+}
+
+func (repository *gormUserRepository) DeactivatedBefore(moment time.Time) []models.User {
+    var users []models.User
+    repository.db.
+        Where("active = ? AND deactivated_at < ? AND anonymized_at IS NULL", false, moment).
+        Find(&users)
+    return users
+}
+
+// Second factors are removed in the same transaction
+func (repository *gormUserRepository) Anonymize(user models.User) error {
+    now := time.Now().UTC()
+    transaction := repository.db.Begin()
+    err := transaction.Model(&user).UpdateColumns(map[string]interface{}{
+        "username": fmt.Sprintf("anonymized%d", user.ID),
+        "first_name": "Anonymized",
+        "last_name": "User",
+        "email": fmt.Sprintf("anonymized%d@anonymized.invalid", user.ID),
+        "passphrase": "",
+        "code_secret": "",
+        "recover_secret": "",
+        "anonymized_at": now,
+        "updated_at": now,
+    }).Error
+    if err != nil {
+        transaction.Rollback()
+        return err
+    }
+    err = transaction.Model(&models.Session{}).Where("user_id = ?", user.ID).UpdateColumns(map[string]interface{}{
+        "ip": "",
+        "user_agent": "",
+        "invalidated": true,
+        "updated_at": now,
+    }).Error
+    if err != nil {
+        transaction.Rollback()
+        return err
+    }
+    if err := transaction.Where("user_id = ?", user.ID).Delete(models.TOTPDevice{}).Error; err != nil {
+        transaction.Rollback()
+        return err
+    }
+    if err := transaction.Where("user_id = ?", user.ID).Delete(models.Credential{}).Error; err != nil {
+        transaction.Rollback()
+        return err
+    }
+    return transaction.Commit().Error
+}
+
+func (repository *gormClientRepository) Create(client *models.Client) error {
+    return repository.db.Create(client).Error
+}
+
+func (repository *gormClientRepository) SaveSecret(client models.Client) error {
+    return repository.db.Model(&client).UpdateColumn("secret", client.Secret).Error
+}
+
+// Column names are quoted by the dialect (`key` is a reserved word in MySQL)
+func (repository *gormClientRepository) findWhere(column string, value interface{}) models.Client {
+    var client models.Client
+    repository.db.Where(map[string]interface{}{column: value}).First(&client)
+    return client
+}
+
+func (repository *gormClientRepository) FindByName(name string) models.Client {
+    return repository.findWhere("name", name)
+}
+
+func (repository *gormClientRepository) FindByKey(key string) models.Client {
+    return repository.findWhere("key", key)
+}
+
+func (repository *gormClientRepository) FindByUUID(uuid string) models.Client {
+    return repository.findWhere("uuid", uuid)
+}
+
+func (repository *gormClientRepository) ActiveForUser(userID uint) []models.Client {
+    var clients []models.Client
+    repository.db.
+        Table("clients").
+        Select("DISTINCT clients.uuid, clients.name, clients.description, clients.canonical_uri").
+        Joins("JOIN sessions ON clients.id = sessions.client_id").
+        Where("sessions.token_type IN (?) AND sessions.invalidated = ? AND sessions.user_id = ?",
+            []string{models.AccessToken, models.RefreshToken}, false, userID).
+        Scan(&clients)
+    return clients
+}
+
+func (repository *gormSessionRepository) Create(session *models.Session) error {
+    result := repository.db.Create(session)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected < 1 {
+        return errors.New("Session was not created")
+    }
+    return nil
+}
+
+func (repository *gormSessionRepository) findWhere(query string, args ...interface{}) models.Session {
+    var session models.Session
+    repository.db.
+        Preload("Client").
+        Preload("User").
+        Preload("User.Client").
+        Preload("User.Language").
+        Where(query, args...).
+        First(&session)
+    return session
+}
+
+func (repository *gormSessionRepository) FindByUUID(uuid string) models.Session {
+    return repository.findWhere("uuid = ? AND invalidated = ?", uuid, false)
+}
+
+// Only the token digest is stored
+func (repository *gormSessionRepository) FindByToken(token, tokenType string) models.Session {
+    return repository.findWhere("token = ? AND token_type = ? AND invalidated = ?", models.DigestToken(token), tokenType, false)
+}
+
+func (repository *gormSessionRepository) Invalidate(session models.Session) error {
+    return repository.db.Model(&session).Select("invalidated").Update("invalidated", true).Error
+}
+
+func (repository *gormSessionRepository) where(filter SessionFilter) *gorm.DB {
+    query := repository.db.Model(&models.Session{}).Where("invalidated = ?", false)
+    if filter.UserID != 0 {
+        query = query.Where("user_id = ?", filter.UserID)
+    }
+    if filter.ClientID != 0 {
+        query = query.Where("client_id = ?", filter.ClientID)
+    }
+    if filter.ParentID != 0 {
+        query = query.Where("parent_id = ?", filter.ParentID)
+    }
+    if len(filter.TokenTypes) > 0 {
+        query = query.Where("token_type IN (?)", filter.TokenTypes)
+    }
+    return query
+}
+
+func (repository *gormSessionRepository) InvalidateWhere(filter SessionFilter) ([]models.Session, error) {
+    var sessions []models.Session
+
+    if filter.empty() {
+        return nil, errEmptySessionFilter
+    }
+    query := repository.where(filter)
+    if err := query.Select("id, uuid, token_type, moment, expires_in").Find(&sessions).Error; err != nil {
+        return nil, err
+    }
+    err := query.UpdateColumns(map[string]interface{}{"invalidated": true, "updated_at": time.Now().UTC()}).Error
+    return sessions, err
+}
+
+func (repository *gormSessionRepository) CountActive(clientID, userID uint) int64 {
+    var count int64
+
+    // A zero ID would count the sessions of every client (or user)
+    if clientID == 0 || userID == 0 {
+        return 0
+    }
+    repository.where(SessionFilter{
+        UserID: userID,
+        ClientID: clientID,
+        TokenTypes: []string{models.AccessToken, models.RefreshToken},
+    }).Count(&count)
+    return count
+}
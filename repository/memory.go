@@ -0,0 +1,406 @@
+package repository
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/earaujoassis/space/models"
+)
+
+// Records shared by the in-memory repositories; associations are resolved on lookups,
+// like the preloading done by the GORM repositories
+type memoryRecords struct {
+    mutex sync.Mutex
+    users []models.User
+    clients []models.Client
+    sessions []models.Session
+}
+
+type memoryUserRepository struct {
+    records *memoryRecords
+}
+
+type memoryClientRepository struct {
+    records *memoryRecords
+}
+
+type memorySessionRepository struct {
+    records *memoryRecords
+}
+
+// In-process repositories, safe for concurrent use; they run the same model callbacks as GORM
+// does (validation and generated values), so they're suitable to test services and grant flows
+func NewMemoryRepositories() Repositories {
+    records := &memoryRecords{}
+    return Repositories{
+        Users: &memoryUserRepository{records: records},
+        Clients: &memoryClientRepository{records: records},
+        Sessions: &memorySessionRepository{records: records},
+    }
+}
+
+// Records are kept in insertion order, so IDs are their positions plus one
+func (records *memoryRecords) user(id uint) models.User {
+    if id == 0 || int(id) > len(records.users) {
+        return models.User{}
+    }
+    user := records.users[id - 1]
+    user.Client = records.client(user.ClientID)
+    return user
+}
+
+func (records *memoryRecords) client(id uint) models.Client {
+    if id == 0 || int(id) > len(records.clients) {
+        return models.Client{}
+    }
+    return records.clients[id - 1]
+}
+
+func (records *memoryRecords) session(index int) models.Session {
+    session := records.sessions[index]
+    session.User = records.user(session.UserID)
+    session.Client = records.client(session.ClientID)
+    return session
+}
+
+func (repository *memoryUserRepository) Create(user *models.User) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if user.ID != 0 {
+        return errors.New("User was already created")
+    }
+    if err := user.Validate(); err != nil {
+        return err
+    }
+    if err := user.Prepare(); err != nil {
+        return err
+    }
+    now := time.Now().UTC()
+    user.ID = uint(len(records.users) + 1)
+    user.CreatedAt = now
+    user.UpdatedAt = now
+    if user.Client.ID != 0 {
+        user.ClientID = user.Client.ID
+    }
+    if user.Language.ID != 0 {
+        user.LanguageID = user.Language.ID
+    }
+    records.users = append(records.users, *user)
+    return nil
+}
+
+func (repository *memoryUserRepository) Save(user *models.User) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if user.ID == 0 || int(user.ID) > len(records.users) {
+        return errors.New("User was not found")
+    }
+    if err := user.Validate(); err != nil {
+        return err
+    }
+    user.UpdatedAt = time.Now().UTC()
+    records.users[user.ID - 1] = *user
+    return nil
+}
+
+func (repository *memoryUserRepository) SavePassphrase(user models.User) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if user.ID == 0 || int(user.ID) > len(records.users) {
+        return errors.New("User was not found")
+    }
+    records.users[user.ID - 1].Passphrase = user.Passphrase
+    return nil
+}
+
+func (repository *memoryUserRepository) findWhere(matches func(user models.User) bool) models.User {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    for _, user := range records.users {
+        if matches(user) {
+            return records.user(user.ID)
+        }
+    }
+    return models.User{}
+}
+
+func (repository *memoryUserRepository) FindByID(id uint) models.User {
+    return repository.findWhere(func(user models.User) bool {
+        return user.ID == id
+    })
+}
+
+func (repository *memoryUserRepository) FindByUUID(uuid string) models.User {
+    return repository.findWhere(func(user models.User) bool {
+        return user.UUID == uuid
+    })
+}
+
+func (repository *memoryUserRepository) FindByPublicId(publicId string) models.User {
+    return repository.findWhere(func(user models.User) bool {
+        return user.PublicId == publicId
+    })
+}
+
+func (repository *memoryUserRepository) FindByAccountHolder(holder string) models.User {
+    return repository.findWhere(func(user models.User) bool {
+        return user.Username == holder || user.Email == holder
+    })
+}
+
+func (repository *memoryUserRepository) DeactivatedBefore(moment time.Time) []models.User {
+    var users []models.User
+
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    for _, user := range records.users {
+        if !user.Active && user.DeactivatedAt != nil && user.DeactivatedAt.Before(moment) && user.AnonymizedAt == nil {
+            users = append(users, user)
+        }
+    }
+    return users
+}
+
+func (repository *memoryUserRepository) Anonymize(user models.User) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if user.ID == 0 || int(user.ID) > len(records.users) {
+        return errors.New("User was not found")
+    }
+    now := time.Now().UTC()
+    stored := &records.users[user.ID - 1]
+    stored.Username = fmt.Sprintf("anonymized%d", user.ID)
+    stored.FirstName = "Anonymized"
+    stored.LastName = "User"
+    stored.Email = fmt.Sprintf("anonymized%d@anonymized.invalid", user.ID)
+    stored.Passphrase = ""
+    stored.CodeSecret = ""
+    stored.RecoverSecret = ""
+    stored.AnonymizedAt = &now
+    stored.UpdatedAt = now
+    for i := range records.sessions {
+        if records.sessions[i].UserID == user.ID {
+            records.sessions[i].Ip = ""
+            records.sessions[i].UserAgent = ""
+            records.sessions[i].Invalidated = true
+            records.sessions[i].UpdatedAt = now
+        }
+    }
+    return nil
+}
+
+func (repository *memoryClientRepository) Create(client *models.Client) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if client.ID != 0 {
+        return errors.New("Client was already created")
+    }
+    if err := client.Validate(); err != nil {
+        return err
+    }
+    if err := client.Prepare(); err != nil {
+        return err
+    }
+    now := time.Now().UTC()
+    client.ID = uint(len(records.clients) + 1)
+    client.CreatedAt = now
+    client.UpdatedAt = now
+    records.clients = append(records.clients, *client)
+    return nil
+}
+
+func (repository *memoryClientRepository) SaveSecret(client models.Client) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if client.ID == 0 || int(client.ID) > len(records.clients) {
+        return errors.New("Client was not found")
+    }
+    records.clients[client.ID - 1].Secret = client.Secret
+    return nil
+}
+
+func (repository *memoryClientRepository) findWhere(matches func(client models.Client) bool) models.Client {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    for _, client := range records.clients {
+        if matches(client) {
+            return client
+        }
+    }
+    return models.Client{}
+}
+
+func (repository *memoryClientRepository) FindByName(name string) models.Client {
+    return repository.findWhere(func(client models.Client) bool {
+        return client.Name == name
+    })
+}
+
+func (repository *memoryClientRepository) FindByKey(key string) models.Client {
+    return repository.findWhere(func(client models.Client) bool {
+        return client.Key == key
+    })
+}
+
+func (repository *memoryClientRepository) FindByUUID(uuid string) models.Client {
+    return repository.findWhere(func(client models.Client) bool {
+        return client.UUID == uuid
+    })
+}
+
+func (repository *memoryClientRepository) ActiveForUser(userID uint) []models.Client {
+    var clients []models.Client
+
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    active := make(map[uint]bool)
+    for _, session := range records.sessions {
+        if session.UserID == userID && !session.Invalidated &&
+                (session.TokenType == models.AccessToken || session.TokenType == models.RefreshToken) &&
+                !active[session.ClientID] {
+            active[session.ClientID] = true
+            clients = append(clients, records.client(session.ClientID))
+        }
+    }
+    return clients
+}
+
+func (repository *memorySessionRepository) Create(session *models.Session) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if session.ID != 0 {
+        return errors.New("Session was already created")
+    }
+    if err := session.Validate(); err != nil {
+        return err
+    }
+    if err := session.Prepare(); err != nil {
+        return err
+    }
+    now := time.Now().UTC()
+    session.ID = uint(len(records.sessions) + 1)
+    session.CreatedAt = now
+    session.UpdatedAt = now
+    if session.User.ID != 0 {
+        session.UserID = session.User.ID
+    }
+    if session.Client.ID != 0 {
+        session.ClientID = session.Client.ID
+    }
+    stored := *session
+    stored.User = models.User{}
+    stored.Client = models.Client{}
+    records.sessions = append(records.sessions, stored)
+    session.RevealToken()
+    return nil
+}
+
+func (repository *memorySessionRepository) findWhere(matches func(session models.Session) bool) models.Session {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    for i, session := range records.sessions {
+        if !session.Invalidated && matches(session) {
+            return records.session(i)
+        }
+    }
+    return models.Session{}
+}
+
+func (repository *memorySessionRepository) FindByUUID(uuid string) models.Session {
+    return repository.findWhere(func(session models.Session) bool {
+        return session.UUID == uuid
+    })
+}
+
+// Only the token digest is stored
+func (repository *memorySessionRepository) FindByToken(token, tokenType string) models.Session {
+    digest := models.DigestToken(token)
+    return repository.findWhere(func(session models.Session) bool {
+        return session.Token == digest && session.TokenType == tokenType
+    })
+}
+
+func (repository *memorySessionRepository) Invalidate(session models.Session) error {
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    if session.ID == 0 || int(session.ID) > len(records.sessions) {
+        return errors.New("Session was not found")
+    }
+    records.sessions[session.ID - 1].Invalidated = true
+    return nil
+}
+
+func (filter SessionFilter) matches(session models.Session) bool {
+    if session.Invalidated ||
+            (filter.UserID != 0 && session.UserID != filter.UserID) ||
+            (filter.ClientID != 0 && session.ClientID != filter.ClientID) ||
+            (filter.ParentID != 0 && session.ParentID != filter.ParentID) {
+        return false
+    }
+    if len(filter.TokenTypes) == 0 {
+        return true
+    }
+    for _, tokenType := range filter.TokenTypes {
+        if session.TokenType == tokenType {
+            return true
+        }
+    }
+    return false
+}
+
+func (repository *memorySessionRepository) InvalidateWhere(filter SessionFilter) ([]models.Session, error) {
+    var sessions []models.Session
+
+    if filter.empty() {
+        return nil, errEmptySessionFilter
+    }
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    now := time.Now().UTC()
+    for i := range records.sessions {
+        if filter.matches(records.sessions[i]) {
+            records.sessions[i].Invalidated = true
+            records.sessions[i].UpdatedAt = now
+            sessions = append(sessions, records.sessions[i])
+        }
+    }
+    return sessions, nil
+}
+
+func (repository *memorySessionRepository) CountActive(clientID, userID uint) int64 {
+    var count int64
+
+    if clientID == 0 || userID == 0 {
+        return 0
+    }
+    records := repository.records
+    records.mutex.Lock()
+    defer records.mutex.Unlock()
+    filter := SessionFilter{
+        UserID: userID,
+        ClientID: clientID,
+        TokenTypes: []string{models.AccessToken, models.RefreshToken},
+    }
+    for _, session := range records.sessions {
+        if filter.matches(session) {
+            count++
+        }
+    }
+    return count
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+    "errors"
+    "time"
+
+    "github.com/earaujoassis/space/models"
+)
+
+// Lookups return a zero value when there's no matching record; callers check its ID
+type UserRepository interface {
+    Create(user *models.User) error
+    // Associations (client and language) are not saved along with the user
+    Save(user *models.User) error
+    SavePassphrase(user models.User) error
+    FindByID(id uint) models.User
+    FindByUUID(uuid string) models.User
+    FindByPublicId(publicId string) models.User
+    FindByAccountHolder(holder string) models.User
+    DeactivatedBefore(moment time.Time) []models.User
+    Anonymize(user models.User) error
+}
+
+type ClientRepository interface {
+    Create(client *models.Client) error
+    SaveSecret(client models.Client) error
+    FindByName(name string) models.Client
+    FindByKey(key string) models.Client
+    FindByUUID(uuid string) models.Client
+    // Clients holding access or refresh tokens of the user which are not invalidated
+    ActiveForUser(userID uint) []models.Client
+}
+
+// Sessions are only found while they're not invalidated; their expiration is left to the callers
+type SessionRepository interface {
+    Create(session *models.Session) error
+    FindByUUID(uuid string) models.Session
+    FindByToken(token, tokenType string) models.Session
+    Invalidate(session models.Session) error
+    // It returns the sessions which were invalidated; the filter must not be empty
+    InvalidateWhere(filter SessionFilter) ([]models.Session, error)
+    // Access and refresh tokens of the user for the client which are not invalidated
+    CountActive(clientID, userID uint) int64
+}
+
+// Zero values match any session
+type SessionFilter struct {
+    UserID uint
+    ClientID uint
+    ParentID uint
+    TokenTypes []string
+}
+
+var errEmptySessionFilter = errors.New("Session filter is empty")
+
+func (filter SessionFilter) empty() bool {
+    return filter.UserID == 0 && filter.ClientID == 0 && filter.ParentID == 0 && len(filter.TokenTypes) == 0
+}
+
+type Repositories struct {
+    Users UserRepository
+    Clients ClientRepository
+    Sessions SessionRepository
+}
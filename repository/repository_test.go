@@ -0,0 +1,148 @@
+package repository
+
+import (
+    "testing"
+
+    "github.com/jinzhu/gorm"
+    _ "github.com/jinzhu/gorm/dialects/sqlite"
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+)
+
+// Both implementations go through the same tests; the GORM one runs against an in-memory SQLite database
+func testingRepositories(t *testing.T) map[string]Repositories {
+    db, err := gorm.Open("sqlite3", ":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Every connection would open a distinct in-memory database
+    db.DB().SetMaxOpenConns(1)
+    db.AutoMigrate(&models.Client{},
+        &models.Language{},
+        &models.User{},
+        &models.Session{},
+        &models.Credential{},
+        &models.TOTPDevice{})
+    return map[string]Repositories{
+        "memory": NewMemoryRepositories(),
+        "gorm": NewGormRepositories(db),
+    }
+}
+
+func createTestingClient(t *testing.T, repositories Repositories) models.Client {
+    client := models.Client{
+        Name: "Saturn",
+        Secret: "saturn-secret",
+        Scopes: models.ReadScope,
+        CanonicalURI: "https://saturn.com",
+        RedirectURI: "https://saturn.com/callback",
+        Type: models.ConfidentialClient,
+    }
+    assert.Nil(t, repositories.Clients.Create(&client), "should create a client")
+    return client
+}
+
+func createTestingSession(t *testing.T, repositories Repositories, client models.Client, tokenType string, parentID uint) models.Session {
+    session := models.Session{
+        Client: client,
+        Ip: "127.0.0.1",
+        UserAgent: "Testing",
+        Scopes: models.ReadScope,
+        TokenType: tokenType,
+        ParentID: parentID,
+    }
+    assert.Nil(t, repositories.Sessions.Create(&session), "should create a session")
+    return session
+}
+
+func TestClientRepository(t *testing.T) {
+    for name, repositories := range testingRepositories(t) {
+        t.Run(name, func(t *testing.T) {
+            testClientRepository(t, repositories)
+        })
+    }
+}
+
+func testClientRepository(t *testing.T, repositories Repositories) {
+    client := createTestingClient(t, repositories)
+
+    assert.NotEqual(t, uint(0), client.ID, "should assign an ID")
+    assert.NotEmpty(t, client.Key, "should generate a key")
+    assert.NotEqual(t, "saturn-secret", client.Secret, "should hash the secret")
+    assert.Equal(t, client.ID, repositories.Clients.FindByKey(client.Key).ID, "should find the client by its key")
+    assert.Equal(t, client.ID, repositories.Clients.FindByName("Saturn").ID, "should find the client by its name")
+    assert.Equal(t, uint(0), repositories.Clients.FindByUUID("unknown").ID, "should not find an unknown client")
+}
+
+func TestSessionRepository(t *testing.T) {
+    for name, repositories := range testingRepositories(t) {
+        t.Run(name, func(t *testing.T) {
+            testSessionRepository(t, repositories)
+        })
+    }
+}
+
+func testSessionRepository(t *testing.T, repositories Repositories) {
+    client := createTestingClient(t, repositories)
+    session := createTestingSession(t, repositories, client, models.AccessToken, 0)
+
+    assert.NotEmpty(t, session.UUID, "should generate an UUID")
+    assert.NotEmpty(t, session.Token, "should hand out the plain token")
+    found := repositories.Sessions.FindByToken(session.Token, models.AccessToken)
+    assert.Equal(t, session.ID, found.ID, "should find the session by its token")
+    assert.Equal(t, models.DigestToken(session.Token), found.Token, "should only store the token digest")
+    assert.Equal(t, client.Key, found.Client.Key, "should resolve the client")
+    assert.Equal(t, uint(0), repositories.Sessions.FindByToken(session.Token, models.RefreshToken).ID, "should match the token type")
+
+    assert.Nil(t, repositories.Sessions.Invalidate(session), "should invalidate the session")
+    assert.Equal(t, uint(0), repositories.Sessions.FindByUUID(session.UUID).ID, "should not find invalidated sessions")
+}
+
+func TestSessionRepositoryInvalidateWhere(t *testing.T) {
+    for name, repositories := range testingRepositories(t) {
+        t.Run(name, func(t *testing.T) {
+            testSessionRepositoryInvalidateWhere(t, repositories)
+        })
+    }
+}
+
+func testSessionRepositoryInvalidateWhere(t *testing.T, repositories Repositories) {
+    client := createTestingClient(t, repositories)
+    parent := createTestingSession(t, repositories, client, models.AccessToken, 0)
+    child := createTestingSession(t, repositories, client, models.AccessToken, parent.ID)
+
+    _, err := repositories.Sessions.InvalidateWhere(SessionFilter{})
+    assert.Equal(t, errEmptySessionFilter, err, "should refuse to invalidate every session")
+    sessions, err := repositories.Sessions.InvalidateWhere(SessionFilter{ParentID: parent.ID})
+    assert.Nil(t, err, "should invalidate the matching sessions")
+    assert.Len(t, sessions, 1, "should return the invalidated sessions")
+    assert.Equal(t, child.UUID, sessions[0].UUID, "should only invalidate the matching sessions")
+    assert.Equal(t, uint(0), repositories.Sessions.FindByUUID(child.UUID).ID, "should have invalidated the child session")
+    assert.Equal(t, parent.ID, repositories.Sessions.FindByUUID(parent.UUID).ID, "should keep the other sessions")
+}
+
+func TestSessionRepositoryCountActive(t *testing.T) {
+    for name, repositories := range testingRepositories(t) {
+        t.Run(name, func(t *testing.T) {
+            testSessionRepositoryCountActive(t, repositories)
+        })
+    }
+}
+
+func testSessionRepositoryCountActive(t *testing.T, repositories Repositories) {
+    client := createTestingClient(t, repositories)
+    session := models.Session{
+        Client: client,
+        UserID: 7,
+        Ip: "127.0.0.1",
+        UserAgent: "Testing",
+        Scopes: models.ReadScope,
+        TokenType: models.AccessToken,
+    }
+    assert.Nil(t, repositories.Sessions.Create(&session), "should create a session")
+
+    assert.Equal(t, int64(1), repositories.Sessions.CountActive(client.ID, 7), "should count the active sessions")
+    assert.Equal(t, int64(0), repositories.Sessions.CountActive(0, 7), "should not count the sessions of an unknown client")
+    assert.Equal(t, int64(0), repositories.Sessions.CountActive(client.ID, 0), "should not count the sessions of an unknown user")
+}
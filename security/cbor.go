@@ -0,0 +1,145 @@
+package security
+
+import (
+    "errors"
+    "math"
+)
+
+const (
+    cborMaxDepth int = 16
+)
+
+// Minimal CBOR (RFC 7049) decoder, enough for WebAuthn attestation objects and COSE keys.
+// Integers are decoded as int64, byte strings as []byte, text strings as string, arrays as
+// []interface{} and maps as map[interface{}]interface{}; indefinite lengths and floats are unsupported
+type cborDecoder struct {
+    data []byte
+    offset int
+}
+
+// Decodes the first CBOR item in data; it also returns how many bytes were consumed
+func DecodeCBOR(data []byte) (interface{}, int, error) {
+    decoder := &cborDecoder{data: data}
+    value, err := decoder.item(0)
+    if err != nil {
+        return nil, 0, err
+    }
+    return value, decoder.offset, nil
+}
+
+func (decoder *cborDecoder) read(length uint64) ([]byte, error) {
+    if length > uint64(len(decoder.data) - decoder.offset) {
+        return nil, errors.New("Unexpected end of CBOR data")
+    }
+    data := decoder.data[decoder.offset:decoder.offset + int(length)]
+    decoder.offset += int(length)
+    return data, nil
+}
+
+func (decoder *cborDecoder) head() (byte, byte, uint64, error) {
+    initial, err := decoder.read(1)
+    if err != nil {
+        return 0, 0, 0, err
+    }
+    major := initial[0] >> 5
+    info := initial[0] & 0x1f
+    switch {
+    case info < 24:
+        return major, info, uint64(info), nil
+    case info <= 27:
+        data, err := decoder.read(1 << (info - 24))
+        if err != nil {
+            return 0, 0, 0, err
+        }
+        var value uint64
+        for _, b := range data {
+            value = value << 8 | uint64(b)
+        }
+        return major, info, value, nil
+    }
+    return 0, 0, 0, errors.New("Unsupported CBOR item")
+}
+
+func (decoder *cborDecoder) item(depth int) (interface{}, error) {
+    if depth > cborMaxDepth {
+        return nil, errors.New("CBOR data is too deep")
+    }
+    major, info, value, err := decoder.head()
+    if err != nil {
+        return nil, err
+    }
+    switch major {
+    case 0:
+        if value > math.MaxInt64 {
+            return nil, errors.New("CBOR integer overflow")
+        }
+        return int64(value), nil
+    case 1:
+        if value > math.MaxInt64 {
+            return nil, errors.New("CBOR integer overflow")
+        }
+        return -1 - int64(value), nil
+    case 2:
+        data, err := decoder.read(value)
+        if err != nil {
+            return nil, err
+        }
+        return append([]byte(nil), data...), nil
+    case 3:
+        data, err := decoder.read(value)
+        if err != nil {
+            return nil, err
+        }
+        return string(data), nil
+    case 4:
+        // Every item takes at least one byte; it avoids allocating for bogus lengths
+        if value > uint64(len(decoder.data) - decoder.offset) {
+            return nil, errors.New("Unexpected end of CBOR data")
+        }
+        array := make([]interface{}, 0, value)
+        for i := uint64(0); i < value; i++ {
+            element, err := decoder.item(depth + 1)
+            if err != nil {
+                return nil, err
+            }
+            array = append(array, element)
+        }
+        return array, nil
+    case 5:
+        if value > uint64(len(decoder.data) - decoder.offset) {
+            return nil, errors.New("Unexpected end of CBOR data")
+        }
+        dictionary := make(map[interface{}]interface{})
+        for i := uint64(0); i < value; i++ {
+            key, err := decoder.item(depth + 1)
+            if err != nil {
+                return nil, err
+            }
+            switch key.(type) {
+            case int64, string:
+            default:
+                return nil, errors.New("Unsupported CBOR map key")
+            }
+            element, err := decoder.item(depth + 1)
+            if err != nil {
+                return nil, err
+            }
+            dictionary[key] = element
+        }
+        return dictionary, nil
+    case 6:
+        return decoder.item(depth + 1)
+    case 7:
+        if info < 24 {
+            switch value {
+            case 20:
+                return false, nil
+            case 21:
+                return true, nil
+            case 22, 23:
+                return nil, nil
+            }
+        }
+    }
+    return nil, errors.New("Unsupported CBOR item")
+}
@@ -0,0 +1,30 @@
+package security
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+    // {1: 2, 3: -7, "fmt": "none", "list": [true, h'0102']}
+    data := []byte{0xa4, 0x01, 0x02, 0x03, 0x26, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e',
+        0x64, 'l', 'i', 's', 't', 0x82, 0xf5, 0x42, 0x01, 0x02}
+    value, length, err := DecodeCBOR(data)
+    assert.Nil(t, err, "should have decoded the CBOR data")
+    assert.Equal(t, len(data), length, "should have consumed every byte")
+    dictionary := value.(map[interface{}]interface{})
+    assert.Equal(t, int64(2), dictionary[int64(1)], "should have decoded an unsigned integer")
+    assert.Equal(t, int64(-7), dictionary[int64(3)], "should have decoded a negative integer")
+    assert.Equal(t, "none", dictionary["fmt"], "should have decoded a text string")
+    assert.Equal(t, []interface{}{true, []byte{0x01, 0x02}}, dictionary["list"], "should have decoded an array")
+}
+
+func TestDecodeInvalidCBOR(t *testing.T) {
+    _, _, err := DecodeCBOR([]byte{0x58, 0x20, 0x01})
+    assert.NotNil(t, err, "should have refused a truncated byte string")
+    _, _, err = DecodeCBOR([]byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+    assert.NotNil(t, err, "should have refused a bogus array length")
+    _, _, err = DecodeCBOR([]byte{0x5f})
+    assert.NotNil(t, err, "should have refused an indefinite length")
+}
@@ -3,8 +3,11 @@ package security
 import (
     "crypto/aes"
     "crypto/cipher"
+    "crypto/hmac"
     "crypto/rand"
+    "crypto/sha256"
     "encoding/base64"
+    "encoding/hex"
     "errors"
     "io"
 )
@@ -44,3 +47,10 @@ func Decrypt(key []byte, encodedText string) ([]byte, error) {
     }
     return data, nil
 }
+
+// Keyed digest (HMAC-SHA256, hex-encoded) used to store bearer tokens at rest
+func TokenDigest(key []byte, token string) string {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(token))
+    return hex.EncodeToString(mac.Sum(nil))
+}
@@ -17,3 +17,13 @@ func TestEncryptDecrypt(t *testing.T) {
     assert.Equal(t, message, string(decrypted), "should have decrypted the message")
     assert.NotEqual(t, message, string(fakeDecrypted), "should have not decrypted the message")
 }
+
+func TestTokenDigest(t *testing.T) {
+    key := []byte("m36mh39DtwvndHtY")
+    token := "DRntLzidomQsKnunEwLtlOOApkUlFgWavODsoTE"
+    digest := TokenDigest(key, token)
+    assert.Equal(t, 64, len(digest), "should have created a hex-encoded SHA-256 digest")
+    assert.Equal(t, digest, TokenDigest(key, token), "should be deterministic")
+    assert.NotEqual(t, digest, TokenDigest([]byte("Y2NvtbNymWRUUnYQ"), token), "should depend on the key")
+    assert.True(t, ValidToken(digest), "should keep a valid token format")
+}
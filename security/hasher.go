@@ -0,0 +1,175 @@
+package security
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "strings"
+
+    "golang.org/x/crypto/argon2"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const (
+    BcryptAlgorithm             string = "bcrypt"
+    Argon2idAlgorithm           string = "argon2id"
+
+    // OWASP recommendations for argon2id (memory in KiB)
+    DefaultArgon2Passes         uint32 = 2
+    DefaultArgon2Memory         uint32 = 19456
+    DefaultArgon2Lanes          uint8 = 1
+
+    argon2SaltSize              int = 16
+    argon2KeySize               uint32 = 32
+)
+
+// Hashes secrets (passphrases and client secrets) with the configured algorithm. Stored hashes
+// identify their algorithm: bcrypt hashes use the modular crypt format (`$2a$<cost>$...`) and
+// argon2id hashes use the PHC string format (`$argon2id$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<hash>`)
+type Hasher struct {
+    Algorithm string
+    BcryptCost int
+    Argon2Passes uint32
+    Argon2Memory uint32
+    Argon2Lanes uint8
+}
+
+type argon2Parameters struct {
+    passes uint32
+    memory uint32
+    lanes uint8
+    salt []byte
+    key []byte
+}
+
+func DefaultHasher() Hasher {
+    return Hasher{
+        Algorithm: BcryptAlgorithm,
+        BcryptCost: bcrypt.DefaultCost,
+        Argon2Passes: DefaultArgon2Passes,
+        Argon2Memory: DefaultArgon2Memory,
+        Argon2Lanes: DefaultArgon2Lanes,
+    }
+}
+
+func HashAlgorithm(hashed string) string {
+    switch {
+    case strings.HasPrefix(hashed, "$argon2id$"):
+        return Argon2idAlgorithm
+    case strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2y$"):
+        return BcryptAlgorithm
+    }
+    return ""
+}
+
+func parseArgon2id(hashed string) (argon2Parameters, error) {
+    var parameters argon2Parameters
+    var version uint32
+    var lanes uint32
+
+    parts := strings.Split(hashed, "$")
+    if len(parts) != 6 || parts[1] != Argon2idAlgorithm {
+        return parameters, errors.New("Invalid argon2id hash")
+    }
+    if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+        return parameters, errors.New("Unsupported argon2id version")
+    }
+    if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parameters.memory, &parameters.passes, &lanes); err != nil {
+        return parameters, errors.New("Invalid argon2id parameters")
+    }
+    if parameters.passes < 1 || lanes < 1 || lanes > 255 || parameters.memory < 8 * lanes {
+        return parameters, errors.New("Invalid argon2id parameters")
+    }
+    parameters.lanes = uint8(lanes)
+    salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+    if err != nil {
+        return parameters, err
+    }
+    key, err := base64.RawStdEncoding.DecodeString(parts[5])
+    if err != nil || len(key) == 0 {
+        return parameters, errors.New("Invalid argon2id hash")
+    }
+    parameters.salt, parameters.key = salt, key
+    return parameters, nil
+}
+
+// Checks the algorithm and its parameters, so a misconfigured hasher is detected before any secret is hashed
+func (hasher Hasher) Validate() error {
+    switch hasher.Algorithm {
+    case BcryptAlgorithm:
+        if hasher.BcryptCost < bcrypt.MinCost || hasher.BcryptCost > bcrypt.MaxCost {
+            return errors.New("Invalid bcrypt cost")
+        }
+        return nil
+    case Argon2idAlgorithm:
+        if hasher.Argon2Passes < 1 || hasher.Argon2Lanes < 1 || hasher.Argon2Memory < 8 * uint32(hasher.Argon2Lanes) {
+            return errors.New("Invalid argon2id parameters")
+        }
+        return nil
+    }
+    return errors.New("Unsupported hash algorithm")
+}
+
+func (hasher Hasher) Hash(secret []byte) (string, error) {
+    switch hasher.Algorithm {
+    case BcryptAlgorithm:
+        crypted, err := bcrypt.GenerateFromPassword(secret, hasher.BcryptCost)
+        if err != nil {
+            return "", err
+        }
+        return string(crypted), nil
+    case Argon2idAlgorithm:
+        if err := hasher.Validate(); err != nil {
+            return "", err
+        }
+        salt := make([]byte, argon2SaltSize)
+        if _, err := rand.Read(salt); err != nil {
+            return "", err
+        }
+        key := argon2.IDKey(secret, salt, hasher.Argon2Passes, hasher.Argon2Memory, hasher.Argon2Lanes, argon2KeySize)
+        return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2idAlgorithm, argon2.Version,
+            hasher.Argon2Memory, hasher.Argon2Passes, hasher.Argon2Lanes,
+            base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+    }
+    return "", errors.New("Unsupported hash algorithm")
+}
+
+// Hashes are verified with the algorithm they identify, regardless of the configured one
+func (hasher Hasher) Verify(hashed string, secret []byte) bool {
+    switch HashAlgorithm(hashed) {
+    case BcryptAlgorithm:
+        return bcrypt.CompareHashAndPassword([]byte(hashed), secret) == nil
+    case Argon2idAlgorithm:
+        parameters, err := parseArgon2id(hashed)
+        if err != nil {
+            return false
+        }
+        key := argon2.IDKey(secret, parameters.salt, parameters.passes, parameters.memory, parameters.lanes, uint32(len(parameters.key)))
+        return subtle.ConstantTimeCompare(key, parameters.key) == 1
+    }
+    return false
+}
+
+// Hashes weaker than the configured algorithm and parameters should be replaced; argon2id
+// hashes are never downgraded to bcrypt
+func (hasher Hasher) NeedsRehash(hashed string) bool {
+    switch HashAlgorithm(hashed) {
+    case BcryptAlgorithm:
+        if hasher.Algorithm != BcryptAlgorithm {
+            return true
+        }
+        cost, err := bcrypt.Cost([]byte(hashed))
+        return err != nil || cost < hasher.BcryptCost
+    case Argon2idAlgorithm:
+        if hasher.Algorithm != Argon2idAlgorithm {
+            return false
+        }
+        parameters, err := parseArgon2id(hashed)
+        return err != nil || parameters.passes < hasher.Argon2Passes ||
+            parameters.memory < hasher.Argon2Memory || parameters.lanes < hasher.Argon2Lanes ||
+            uint32(len(parameters.key)) < argon2KeySize
+    }
+    return true
+}
@@ -0,0 +1,59 @@
+package security
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestBcryptHasher(t *testing.T) {
+    hasher := Hasher{Algorithm: BcryptAlgorithm, BcryptCost: 4}
+    hashed, err := hasher.Hash([]byte("correct horse"))
+    assert.Nil(t, err, "should have hashed the secret")
+    assert.Equal(t, BcryptAlgorithm, HashAlgorithm(hashed), "should identify a bcrypt hash")
+    assert.True(t, hasher.Verify(hashed, []byte("correct horse")), "should verify the secret")
+    assert.False(t, hasher.Verify(hashed, []byte("wrong horse")), "should refuse another secret")
+    assert.False(t, hasher.NeedsRehash(hashed), "should keep a hash with the configured cost")
+    stronger := Hasher{Algorithm: BcryptAlgorithm, BcryptCost: 5}
+    assert.True(t, stronger.NeedsRehash(hashed), "should rehash a hash with a lower cost")
+}
+
+func TestArgon2idHasher(t *testing.T) {
+    hasher := Hasher{Algorithm: Argon2idAlgorithm, Argon2Passes: 1, Argon2Memory: 64, Argon2Lanes: 1}
+    hashed, err := hasher.Hash([]byte("correct horse"))
+    assert.Nil(t, err, "should have hashed the secret")
+    assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$"), "should use the PHC string format")
+    assert.Equal(t, Argon2idAlgorithm, HashAlgorithm(hashed), "should identify an argon2id hash")
+    assert.True(t, hasher.Verify(hashed, []byte("correct horse")), "should verify the secret")
+    assert.False(t, hasher.Verify(hashed, []byte("wrong horse")), "should refuse another secret")
+    assert.False(t, hasher.NeedsRehash(hashed), "should keep a hash with the configured parameters")
+    stronger := Hasher{Algorithm: Argon2idAlgorithm, Argon2Passes: 2, Argon2Memory: 64, Argon2Lanes: 1}
+    assert.True(t, stronger.NeedsRehash(hashed), "should rehash a hash with fewer passes")
+    bcryptHasher := Hasher{Algorithm: BcryptAlgorithm, BcryptCost: 12}
+    assert.False(t, bcryptHasher.NeedsRehash(hashed), "should not downgrade to bcrypt")
+    assert.True(t, bcryptHasher.Verify(hashed, []byte("correct horse")), "should verify regardless of the configured algorithm")
+    assert.False(t, hasher.Verify(hashed[:len(hashed) - 4], []byte("correct horse")), "should refuse a tampered hash")
+}
+
+func TestUpgradeFromBcrypt(t *testing.T) {
+    bcryptHasher := Hasher{Algorithm: BcryptAlgorithm, BcryptCost: 4}
+    hashed, _ := bcryptHasher.Hash([]byte("correct horse"))
+    hasher := Hasher{Algorithm: Argon2idAlgorithm, Argon2Passes: 1, Argon2Memory: 64, Argon2Lanes: 1}
+    assert.True(t, hasher.Verify(hashed, []byte("correct horse")), "should verify a bcrypt hash")
+    assert.True(t, hasher.NeedsRehash(hashed), "should upgrade a bcrypt hash to argon2id")
+    assert.True(t, hasher.NeedsRehash("plaintext"), "should rehash an unknown format")
+    assert.False(t, hasher.Verify("plaintext", []byte("plaintext")), "should refuse an unknown format")
+    _, err := Hasher{Algorithm: "md5"}.Hash([]byte("correct horse"))
+    assert.NotNil(t, err, "should refuse an unsupported algorithm")
+}
+
+func TestValidateHasher(t *testing.T) {
+    assert.Nil(t, DefaultHasher().Validate(), "should accept the default hasher")
+    assert.Nil(t, Hasher{Algorithm: Argon2idAlgorithm, Argon2Passes: 1, Argon2Memory: 64, Argon2Lanes: 1}.Validate(),
+        "should accept argon2id parameters")
+    assert.NotNil(t, Hasher{Algorithm: BcryptAlgorithm, BcryptCost: 40}.Validate(), "should refuse a bcrypt cost out of range")
+    assert.NotNil(t, Hasher{Algorithm: Argon2idAlgorithm, Argon2Passes: 1, Argon2Memory: 8, Argon2Lanes: 4}.Validate(),
+        "should refuse less than 8 KiB of memory per lane")
+    assert.NotNil(t, Hasher{Algorithm: "argon2"}.Validate(), "should refuse an unsupported algorithm")
+}
@@ -0,0 +1,274 @@
+package security
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/json"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "math/big"
+    "strings"
+    "time"
+)
+
+const (
+    RS256 string = "RS256"
+    ES256 string = "ES256"
+
+    es256CoordinateSize int = 32
+)
+
+type JSONWebKey struct {
+    Kty string                  `json:"kty"`
+    Kid string                  `json:"kid"`
+    Use string                  `json:"use"`
+    Alg string                  `json:"alg"`
+    N string                    `json:"n,omitempty"`
+    E string                    `json:"e,omitempty"`
+    Crv string                  `json:"crv,omitempty"`
+    X string                    `json:"x,omitempty"`
+    Y string                    `json:"y,omitempty"`
+}
+
+type jwtHeader struct {
+    Alg string                  `json:"alg"`
+    Typ string                  `json:"typ"`
+    Kid string                  `json:"kid"`
+}
+
+func encodeSegment(data []byte) string {
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+    return base64.RawURLEncoding.DecodeString(segment)
+}
+
+func paddedBytes(value *big.Int, size int) []byte {
+    data := value.Bytes()
+    if len(data) >= size {
+        return data
+    }
+    padded := make([]byte, size)
+    copy(padded[size - len(data):], data)
+    return padded
+}
+
+// Only RSA (RS256) and P-256 (ES256) keys are supported
+func SigningAlgorithm(key crypto.PublicKey) string {
+    switch publicKey := key.(type) {
+    case *rsa.PublicKey:
+        return RS256
+    case *ecdsa.PublicKey:
+        if publicKey.Curve == elliptic.P256() {
+            return ES256
+        }
+    }
+    return ""
+}
+
+func ParsePrivateKey(pemData []byte) (crypto.Signer, error) {
+    block, _ := pem.Decode(pemData)
+    if block == nil {
+        return nil, errors.New("Invalid PEM data")
+    }
+    switch block.Type {
+    case "RSA PRIVATE KEY":
+        key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+        if err != nil {
+            return nil, err
+        }
+        return key, nil
+    case "EC PRIVATE KEY":
+        key, err := x509.ParseECPrivateKey(block.Bytes)
+        if err != nil {
+            return nil, err
+        }
+        return key, nil
+    case "PRIVATE KEY":
+        key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+        if err != nil {
+            return nil, err
+        }
+        if signer, ok := key.(crypto.Signer); ok && SigningAlgorithm(signer.Public()) != "" {
+            return signer, nil
+        }
+    }
+    return nil, errors.New("Unsupported private key")
+}
+
+func GeneratePrivateKey(algorithm string) (crypto.Signer, error) {
+    switch algorithm {
+    case RS256:
+        key, err := rsa.GenerateKey(rand.Reader, 2048)
+        if err != nil {
+            return nil, err
+        }
+        return key, nil
+    case ES256:
+        key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+        if err != nil {
+            return nil, err
+        }
+        return key, nil
+    }
+    return nil, errors.New("Unsupported signing algorithm")
+}
+
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+    switch privateKey := key.(type) {
+    case *rsa.PrivateKey:
+        return pem.EncodeToMemory(&pem.Block{
+            Type: "RSA PRIVATE KEY",
+            Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+        }), nil
+    case *ecdsa.PrivateKey:
+        der, err := x509.MarshalECPrivateKey(privateKey)
+        if err != nil {
+            return nil, err
+        }
+        return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+    }
+    return nil, errors.New("Unsupported private key")
+}
+
+// Key identifier according to the JWK Thumbprint (RFC 7638)
+func KeyThumbprint(key crypto.PublicKey) string {
+    var members string
+    switch publicKey := key.(type) {
+    case *rsa.PublicKey:
+        members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+            encodeSegment(big.NewInt(int64(publicKey.E)).Bytes()),
+            encodeSegment(publicKey.N.Bytes()))
+    case *ecdsa.PublicKey:
+        members = fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`,
+            encodeSegment(paddedBytes(publicKey.X, es256CoordinateSize)),
+            encodeSegment(paddedBytes(publicKey.Y, es256CoordinateSize)))
+    default:
+        return ""
+    }
+    digest := sha256.Sum256([]byte(members))
+    return encodeSegment(digest[:])
+}
+
+func PublicJSONWebKey(key crypto.PublicKey, kid string) (JSONWebKey, error) {
+    switch publicKey := key.(type) {
+    case *rsa.PublicKey:
+        return JSONWebKey{
+            Kty: "RSA",
+            Kid: kid,
+            Use: "sig",
+            Alg: RS256,
+            N: encodeSegment(publicKey.N.Bytes()),
+            E: encodeSegment(big.NewInt(int64(publicKey.E)).Bytes()),
+        }, nil
+    case *ecdsa.PublicKey:
+        if SigningAlgorithm(publicKey) == ES256 {
+            return JSONWebKey{
+                Kty: "EC",
+                Kid: kid,
+                Use: "sig",
+                Alg: ES256,
+                Crv: "P-256",
+                X: encodeSegment(paddedBytes(publicKey.X, es256CoordinateSize)),
+                Y: encodeSegment(paddedBytes(publicKey.Y, es256CoordinateSize)),
+            }, nil
+        }
+    }
+    return JSONWebKey{}, errors.New("Unsupported public key")
+}
+
+func SignJWT(claims map[string]interface{}, key crypto.Signer, kid string) (string, error) {
+    var signature []byte
+
+    algorithm := SigningAlgorithm(key.Public())
+    if algorithm == "" {
+        return "", errors.New("Unsupported signing key")
+    }
+    header, err := json.Marshal(jwtHeader{Alg: algorithm, Typ: "JWT", Kid: kid})
+    if err != nil {
+        return "", err
+    }
+    payload, err := json.Marshal(claims)
+    if err != nil {
+        return "", err
+    }
+    signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+    digest := sha256.Sum256([]byte(signingInput))
+    switch privateKey := key.(type) {
+    case *rsa.PrivateKey:
+        signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+        if err != nil {
+            return "", err
+        }
+    case *ecdsa.PrivateKey:
+        r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+        if err != nil {
+            return "", err
+        }
+        signature = append(paddedBytes(r, es256CoordinateSize), paddedBytes(s, es256CoordinateSize)...)
+    default:
+        return "", errors.New("Unsupported signing key")
+    }
+    return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Verifies the signature with the key identified by the `kid` header and checks the `exp` claim, when available
+func VerifyJWT(token string, keys map[string]crypto.PublicKey) (map[string]interface{}, error) {
+    var header jwtHeader
+    var claims map[string]interface{}
+
+    segments := strings.Split(token, ".")
+    if len(segments) != 3 {
+        return nil, errors.New("Malformed token")
+    }
+    headerData, err := decodeSegment(segments[0])
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(headerData, &header); err != nil {
+        return nil, err
+    }
+    publicKey, ok := keys[header.Kid]
+    if !ok || header.Alg != SigningAlgorithm(publicKey) {
+        return nil, errors.New("Unknown signing key")
+    }
+    signature, err := decodeSegment(segments[2])
+    if err != nil {
+        return nil, err
+    }
+    digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+    switch verificationKey := publicKey.(type) {
+    case *rsa.PublicKey:
+        if err := rsa.VerifyPKCS1v15(verificationKey, crypto.SHA256, digest[:], signature); err != nil {
+            return nil, errors.New("Invalid signature")
+        }
+    case *ecdsa.PublicKey:
+        if len(signature) != 2 * es256CoordinateSize {
+            return nil, errors.New("Invalid signature")
+        }
+        r := new(big.Int).SetBytes(signature[:es256CoordinateSize])
+        s := new(big.Int).SetBytes(signature[es256CoordinateSize:])
+        if !ecdsa.Verify(verificationKey, digest[:], r, s) {
+            return nil, errors.New("Invalid signature")
+        }
+    }
+    payload, err := decodeSegment(segments[1])
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(payload, &claims); err != nil {
+        return nil, err
+    }
+    if exp, ok := claims["exp"].(float64); ok && int64(exp) < time.Now().UTC().Unix() {
+        return nil, errors.New("Token is expired")
+    }
+    return claims, nil
+}
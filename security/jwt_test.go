@@ -0,0 +1,72 @@
+package security
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/pem"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSignVerifyJWT(t *testing.T) {
+    rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+    ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    keys := map[string]crypto.PublicKey{
+        "rsa": rsaKey.Public(),
+        "ec": ecKey.Public(),
+    }
+    claims := map[string]interface{}{
+        "sub": "user",
+        "exp": time.Now().UTC().Unix() + 60,
+    }
+
+    for kid, key := range map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey} {
+        token, err := SignJWT(claims, key, kid)
+        assert.Nil(t, err, "should have signed the token")
+        verified, err := VerifyJWT(token, keys)
+        assert.Nil(t, err, "should have verified the token")
+        assert.Equal(t, "user", verified["sub"], "should have returned the claims")
+        _, err = VerifyJWT(token + "x", keys)
+        assert.NotNil(t, err, "should not verify a tampered token")
+    }
+
+    forged, _ := SignJWT(claims, otherKey, "ec")
+    _, err := VerifyJWT(forged, keys)
+    assert.NotNil(t, err, "should not verify a token signed by an unknown key")
+
+    unknown, _ := SignJWT(claims, ecKey, "unknown")
+    _, err = VerifyJWT(unknown, keys)
+    assert.NotNil(t, err, "should not verify a token with an unknown kid")
+
+    expired, _ := SignJWT(map[string]interface{}{"exp": time.Now().UTC().Unix() - 60}, ecKey, "ec")
+    _, err = VerifyJWT(expired, keys)
+    assert.NotNil(t, err, "should not verify an expired token")
+}
+
+func TestParsePrivateKey(t *testing.T) {
+    ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    der, _ := x509.MarshalECPrivateKey(ecKey)
+    pemData := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+    parsed, err := ParsePrivateKey(pemData)
+    assert.Nil(t, err, "should have parsed the private key")
+    assert.Equal(t, ES256, SigningAlgorithm(parsed.Public()), "should have parsed a P-256 key")
+    assert.Equal(t, KeyThumbprint(ecKey.Public()), KeyThumbprint(parsed.Public()), "should keep the same thumbprint")
+    _, err = ParsePrivateKey([]byte("not a key"))
+    assert.NotNil(t, err, "should not parse invalid data")
+}
+
+func TestPublicJSONWebKey(t *testing.T) {
+    rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+    jwk, err := PublicJSONWebKey(rsaKey.Public(), "kid")
+    assert.Nil(t, err, "should have created the JWK")
+    assert.Equal(t, "RSA", jwk.Kty, "should be a RSA key")
+    assert.Equal(t, "AQAB", jwk.E, "should encode the public exponent")
+    assert.Equal(t, RS256, jwk.Alg, "should be used with RS256")
+}
@@ -0,0 +1,131 @@
+package security
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "errors"
+    "io"
+    "regexp"
+    "strings"
+)
+
+const (
+    envelopeVersion string = "v1"
+)
+
+var keyIdPattern = regexp.MustCompile("^[a-zA-Z0-9]+$")
+
+// Encryption keys identified by a key id; the primary key encrypts, every key decrypts.
+// Ciphertexts use the envelope format `v1:<kid>:<base64(nonce || AES-GCM ciphertext)>`
+type Keyring struct {
+    primary string
+    keys map[string][]byte
+    legacyKey []byte
+}
+
+func validKey(key []byte) bool {
+    return len(key) == 16 || len(key) == 24 || len(key) == 32
+}
+
+func NewKeyring(primary string, keys map[string][]byte, legacyKey []byte) (*Keyring, error) {
+    if _, ok := keys[primary]; !ok {
+        return nil, errors.New("Primary key is not in the keyring")
+    }
+    for kid, key := range keys {
+        if !keyIdPattern.MatchString(kid) || !validKey(key) {
+            return nil, errors.New("Invalid key in the keyring")
+        }
+    }
+    return &Keyring{primary: primary, keys: keys, legacyKey: legacyKey}, nil
+}
+
+// Parses a comma-separated list of `kid:base64-key` pairs; the first key is the primary one
+func ParseKeyring(value string, legacyKey []byte) (*Keyring, error) {
+    var primary string
+    keys := make(map[string][]byte)
+
+    for _, entry := range strings.Split(value, ",") {
+        pair := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+        if len(pair) != 2 {
+            return nil, errors.New("Invalid key in the keyring")
+        }
+        key, err := base64.StdEncoding.DecodeString(pair[1])
+        if err != nil {
+            return nil, err
+        }
+        if primary == "" {
+            primary = pair[0]
+        }
+        keys[pair[0]] = key
+    }
+    return NewKeyring(primary, keys, legacyKey)
+}
+
+func (keyring *Keyring) Primary() string {
+    return keyring.primary
+}
+
+func (keyring *Keyring) Encrypt(text []byte) (string, error) {
+    aead, err := newGCM(keyring.keys[keyring.primary])
+    if err != nil {
+        return "", err
+    }
+    nonce := make([]byte, aead.NonceSize())
+    if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+        return "", err
+    }
+    // The key id is authenticated as additional data, so envelopes can't be moved between keys
+    ciphertext := aead.Seal(nonce, nonce, text, []byte(keyring.primary))
+    return strings.Join([]string{envelopeVersion, keyring.primary, base64.StdEncoding.EncodeToString(ciphertext)}, ":"), nil
+}
+
+// Envelopes are opened with the key they name; legacy AES-CFB ciphertexts are still supported
+func (keyring *Keyring) Decrypt(envelope string) ([]byte, error) {
+    if IsLegacyCiphertext(envelope) {
+        if keyring.legacyKey == nil {
+            return nil, errors.New("There's no legacy key in the keyring")
+        }
+        return Decrypt(keyring.legacyKey, envelope)
+    }
+    parts := strings.SplitN(envelope, ":", 3)
+    if len(parts) != 3 || parts[0] != envelopeVersion {
+        return nil, errors.New("Unsupported envelope format")
+    }
+    key, ok := keyring.keys[parts[1]]
+    if !ok {
+        return nil, errors.New("Unknown encryption key")
+    }
+    ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, err
+    }
+    aead, err := newGCM(key)
+    if err != nil {
+        return nil, err
+    }
+    if len(ciphertext) < aead.NonceSize() {
+        return nil, errors.New("Ciphertext too short")
+    }
+    nonce := ciphertext[:aead.NonceSize()]
+    return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], []byte(parts[1]))
+}
+
+// Ciphertexts using the legacy format or a non-primary key should be re-encrypted
+func (keyring *Keyring) NeedsReencryption(envelope string) bool {
+    return !strings.HasPrefix(envelope, envelopeVersion + ":" + keyring.primary + ":")
+}
+
+// Legacy ciphertexts are plain base64, so they never contain the envelope separator
+func IsLegacyCiphertext(value string) bool {
+    return !strings.Contains(value, ":")
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
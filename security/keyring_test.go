@@ -0,0 +1,54 @@
+package security
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestKeyringEncryptDecrypt(t *testing.T) {
+    message := "Two wrongs don't make a right"
+    keyring, err := NewKeyring("k1", map[string][]byte{"k1": []byte("m36mh39DtwvndHtY")}, nil)
+    assert.Nil(t, err, "should have created the keyring")
+    envelope, _ := keyring.Encrypt([]byte(message))
+    decrypted, err := keyring.Decrypt(envelope)
+    assert.Nil(t, err, "should have decrypted the envelope")
+    assert.Equal(t, message, string(decrypted), "should have decrypted the message")
+    assert.False(t, IsLegacyCiphertext(envelope), "should have used the envelope format")
+    assert.False(t, keyring.NeedsReencryption(envelope), "should have used the primary key")
+}
+
+func TestKeyringDetectsTampering(t *testing.T) {
+    keyring, _ := NewKeyring("k1", map[string][]byte{"k1": []byte("m36mh39DtwvndHtY")}, nil)
+    envelope, _ := keyring.Encrypt([]byte("Two wrongs don't make a right"))
+    tampered := []byte(envelope)
+    tampered[len(tampered) - 3] ^= 0x01
+    _, err := keyring.Decrypt(string(tampered))
+    assert.NotNil(t, err, "should have detected the tampered ciphertext")
+    _, err = keyring.Decrypt("v1:k2" + envelope[len("v1:k1"):])
+    assert.NotNil(t, err, "should have refused an unknown key id")
+}
+
+func TestKeyringRotation(t *testing.T) {
+    message := "Two wrongs don't make a right"
+    oldKeyring, _ := NewKeyring("k1", map[string][]byte{"k1": []byte("m36mh39DtwvndHtY")}, nil)
+    envelope, _ := oldKeyring.Encrypt([]byte(message))
+    keyring, _ := ParseKeyring("k2:WTJOdnRiTnltV1JVVW5ZUQ==, k1:bTM2bWgzOUR0d3ZuZEh0WQ==", nil)
+    assert.Equal(t, "k2", keyring.Primary(), "should have used the first key as the primary one")
+    decrypted, err := keyring.Decrypt(envelope)
+    assert.Nil(t, err, "should have decrypted with a previous key")
+    assert.Equal(t, message, string(decrypted), "should have decrypted the message")
+    assert.True(t, keyring.NeedsReencryption(envelope), "should have flagged a non-primary key")
+}
+
+func TestKeyringLegacyCiphertext(t *testing.T) {
+    message := "Two wrongs don't make a right"
+    legacyKey := []byte("m36mh39DtwvndHtY")
+    legacy, _ := Encrypt(legacyKey, []byte(message))
+    keyring, _ := NewKeyring("k1", map[string][]byte{"k1": []byte("Y2NvtbNymWRUUnYQ")}, legacyKey)
+    decrypted, err := keyring.Decrypt(legacy)
+    assert.True(t, IsLegacyCiphertext(legacy), "should have detected the legacy format")
+    assert.Nil(t, err, "should have decrypted the legacy ciphertext")
+    assert.Equal(t, message, string(decrypted), "should have decrypted the message")
+    assert.True(t, keyring.NeedsReencryption(legacy), "should have flagged the legacy format")
+}
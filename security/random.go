@@ -0,0 +1,45 @@
+package security
+
+import (
+    "crypto/rand"
+    "encoding/base64"
+    "errors"
+    "math/big"
+)
+
+const (
+    AlphanumericAlphabet        string = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+    UpperAlphanumericAlphabet   string = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+    URLSafeAlphabet             string = AlphanumericAlphabet + "-_"
+)
+
+// Random strings are drawn from crypto/rand; each character is uniformly picked from the alphabet
+func GenerateRandomString(length int, alphabet string) (string, error) {
+    if length < 0 || len(alphabet) < 2 {
+        return "", errors.New("Invalid length or alphabet")
+    }
+    size := big.NewInt(int64(len(alphabet)))
+    random := make([]byte, length)
+    for i := range random {
+        index, err := rand.Int(rand.Reader, size)
+        if err != nil {
+            return "", err
+        }
+        random[i] = alphabet[index.Int64()]
+    }
+    return string(random), nil
+}
+
+// Alphanumeric tokens keep the format accepted by ValidToken
+func GenerateToken(length int) (string, error) {
+    return GenerateRandomString(length, AlphanumericAlphabet)
+}
+
+// URL-safe base64 (RFC 4648, section 5), without padding, for the given amount of random bytes
+func GenerateURLSafeToken(size int) (string, error) {
+    random := make([]byte, size)
+    if _, err := rand.Read(random); err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(random), nil
+}
@@ -0,0 +1,33 @@
+package security
+
+import (
+    "regexp"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRandomString(t *testing.T) {
+    random, err := GenerateRandomString(32, UpperAlphanumericAlphabet)
+    assert.Nil(t, err, "should have generated a random string")
+    assert.Equal(t, 32, len(random), "should have the requested length")
+    assert.True(t, regexp.MustCompile("^[A-Z0-9]+$").MatchString(random), "should only use the given alphabet")
+    _, err = GenerateRandomString(32, "a")
+    assert.NotNil(t, err, "should require an alphabet with at least two characters")
+}
+
+func TestGenerateToken(t *testing.T) {
+    token, err := GenerateToken(64)
+    anotherToken, _ := GenerateToken(64)
+    assert.Nil(t, err, "should have generated a token")
+    assert.Equal(t, 64, len(token), "should have the requested length")
+    assert.True(t, ValidToken(token), "should keep a valid token format")
+    assert.NotEqual(t, token, anotherToken, "should have generated different tokens")
+}
+
+func TestGenerateURLSafeToken(t *testing.T) {
+    token, err := GenerateURLSafeToken(32)
+    assert.Nil(t, err, "should have generated a token")
+    assert.Equal(t, 43, len(token), "should have encoded 32 bytes without padding")
+    assert.True(t, regexp.MustCompile(`^[A-Za-z0-9\-_]+$`).MatchString(token), "should be URL-safe")
+}
@@ -0,0 +1,45 @@
+package security
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+func expiringValueSignature(key []byte, purpose, value string, expiresAt int64) string {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(fmt.Sprintf("%s.%s.%d", purpose, value, expiresAt)))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Signed values carry their expiration moment, as `<value>.<expires at>.<signature>`; the purpose
+// is part of the signature, so a value signed for one purpose is not accepted for another one
+func SignExpiringValue(key []byte, purpose, value string, expiresAt int64) string {
+    return fmt.Sprintf("%s.%d.%s", value, expiresAt, expiringValueSignature(key, purpose, value, expiresAt))
+}
+
+func VerifyExpiringValue(key []byte, purpose, signed string) (string, error) {
+    parts := strings.Split(signed, ".")
+    if len(parts) < 3 {
+        return "", errors.New("Malformed signed value")
+    }
+    signature := parts[len(parts) - 1]
+    value := strings.Join(parts[:len(parts) - 2], ".")
+    expiresAt, err := strconv.ParseInt(parts[len(parts) - 2], 10, 64)
+    if err != nil {
+        return "", errors.New("Malformed signed value")
+    }
+    expected := expiringValueSignature(key, purpose, value, expiresAt)
+    if !hmac.Equal([]byte(signature), []byte(expected)) {
+        return "", errors.New("Invalid signature")
+    }
+    if expiresAt < time.Now().UTC().Unix() {
+        return "", errors.New("Signed value is expired")
+    }
+    return value, nil
+}
@@ -0,0 +1,30 @@
+package security
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSignExpiringValue(t *testing.T) {
+    key := []byte("m36mh39DtwvndHtY")
+    value := "2ae4f3e7-0c3b-4b0e-8c4e-1f5b6a7c8d9e"
+    signed := SignExpiringValue(key, "user.confirm", value, time.Now().UTC().Unix() + 60)
+    verified, err := VerifyExpiringValue(key, "user.confirm", signed)
+    assert.Nil(t, err, "should have verified the signed value")
+    assert.Equal(t, value, verified, "should have returned the original value")
+    _, err = VerifyExpiringValue(key, "password.reset", signed)
+    assert.NotNil(t, err, "should depend on the purpose")
+    _, err = VerifyExpiringValue([]byte("Y2NvtbNymWRUUnYQ"), "user.confirm", signed)
+    assert.NotNil(t, err, "should depend on the key")
+    _, err = VerifyExpiringValue(key, "user.confirm", "a" + signed)
+    assert.NotNil(t, err, "should have detected the tampered value")
+}
+
+func TestExpiredSignedValue(t *testing.T) {
+    key := []byte("m36mh39DtwvndHtY")
+    signed := SignExpiringValue(key, "user.confirm", "value", time.Now().UTC().Unix() - 1)
+    _, err := VerifyExpiringValue(key, "user.confirm", signed)
+    assert.NotNil(t, err, "should have refused an expired value")
+}
@@ -0,0 +1,59 @@
+package security
+
+import (
+    "crypto/hmac"
+    "crypto/sha1"
+    "crypto/subtle"
+    "encoding/base32"
+    "encoding/binary"
+    "fmt"
+    "strings"
+    "time"
+)
+
+const (
+    totpPeriod      int64 = 30
+    totpSkew        int64 = 1
+    totpDigits      int = 6
+)
+
+// Time steps (RFC 6238, section 4.2) are counted since the Unix epoch
+func TOTPStep(moment time.Time) int64 {
+    return moment.Unix() / totpPeriod
+}
+
+// HOTP value (RFC 4226, section 5.3) for the given time step; secrets are base32-encoded
+func TOTPCode(secret string, step int64) (string, error) {
+    key, err := base32.StdEncoding.WithPadding(base32.NoPadding).
+        DecodeString(strings.TrimRight(strings.ToUpper(secret), "="))
+    if err != nil {
+        return "", err
+    }
+    counter := make([]byte, 8)
+    binary.BigEndian.PutUint64(counter, uint64(step))
+    mac := hmac.New(sha1.New, key)
+    mac.Write(counter)
+    sum := mac.Sum(nil)
+    offset := sum[len(sum) - 1] & 0x0f
+    value := binary.BigEndian.Uint32(sum[offset:offset + 4]) & 0x7fffffff
+    return fmt.Sprintf("%06d", value % 1000000), nil
+}
+
+// Validates a passcode within one time step of skew; it returns the time step it matched,
+// so callers are able to refuse passcodes that were already used
+func ValidateTOTP(passcode, secret string, moment time.Time) (int64, bool) {
+    if len(passcode) != totpDigits {
+        return 0, false
+    }
+    current := TOTPStep(moment)
+    for step := current - totpSkew; step <= current + totpSkew; step++ {
+        code, err := TOTPCode(secret, step)
+        if err != nil {
+            return 0, false
+        }
+        if subtle.ConstantTimeCompare([]byte(code), []byte(passcode)) == 1 {
+            return step, true
+        }
+    }
+    return 0, false
+}
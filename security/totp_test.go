@@ -0,0 +1,51 @@
+package security
+
+import (
+    "encoding/base32"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// RFC 6238, appendix B (SHA-1), truncated to six digits
+func TestTOTPCode(t *testing.T) {
+    secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+    vectors := map[int64]string{
+        59: "287082",
+        1111111109: "081804",
+        1111111111: "050471",
+        1234567890: "005924",
+        2000000000: "279037",
+    }
+    for moment, expected := range vectors {
+        code, err := TOTPCode(secret, TOTPStep(time.Unix(moment, 0)))
+        assert.Nil(t, err, "should have generated a code")
+        assert.Equal(t, expected, code, "should match the RFC 6238 test vector")
+    }
+    _, err := TOTPCode("not base32!", 1)
+    assert.NotNil(t, err, "should refuse an invalid secret")
+}
+
+func TestValidateTOTP(t *testing.T) {
+    secret := "JBSWY3DPEHPK3PXP"
+    moment := time.Unix(1500000000, 0)
+    current := TOTPStep(moment)
+
+    code, _ := TOTPCode(secret, current)
+    step, ok := ValidateTOTP(code, secret, moment)
+    assert.True(t, ok, "should accept the current passcode")
+    assert.Equal(t, current, step, "should return the current time step")
+
+    code, _ = TOTPCode(secret, current - 1)
+    step, ok = ValidateTOTP(code, secret, moment)
+    assert.True(t, ok, "should accept the previous passcode")
+    assert.Equal(t, current - 1, step, "should return the previous time step")
+
+    code, _ = TOTPCode(secret, current - 2)
+    _, ok = ValidateTOTP(code, secret, moment)
+    assert.False(t, ok, "should refuse a passcode out of the window")
+
+    _, ok = ValidateTOTP("12345", secret, moment)
+    assert.False(t, ok, "should refuse a passcode with the wrong length")
+}
@@ -23,6 +23,20 @@ func ValidToken(token string) bool {
     return ValidRandomString(token)
 }
 
+func ValidJWT(token string) bool {
+    r := regexp.MustCompile(`^[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+$`)
+    return r.MatchString(token)
+}
+
+func ValidCodeVerifier(verifier string) bool {
+    r := regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+    return r.MatchString(verifier)
+}
+
+func ValidCodeChallenge(challenge string) bool {
+    return ValidCodeVerifier(challenge)
+}
+
 func ValidEmail(email string) bool {
     r := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
     return r.MatchString(email)
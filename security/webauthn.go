@@ -0,0 +1,258 @@
+package security
+
+import (
+    "bytes"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/asn1"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "math/big"
+)
+
+const (
+    WebAuthnCreate                      string = "webauthn.create"
+    WebAuthnGet                         string = "webauthn.get"
+
+    COSEAlgorithmES256                  int64 = -7
+    COSEAlgorithmRS256                  int64 = -257
+
+    coseKeyType                         int64 = 1
+    coseKeyAlgorithm                    int64 = 3
+    coseKeyEC2                          int64 = 2
+    coseKeyRSA                          int64 = 3
+    coseCurveP256                       int64 = 1
+
+    authenticatorUserPresent            byte = 0x01
+    authenticatorAttestedData           byte = 0x40
+
+    authenticatorDataMinLength          int = 37
+)
+
+type AuthenticatorData struct {
+    RPIDHash []byte
+    Flags byte
+    SignCount uint32
+    CredentialID []byte
+    CredentialPublicKey []byte
+}
+
+type WebAuthnCredential struct {
+    ID []byte
+    PublicKey []byte
+    SignCount uint32
+}
+
+type collectedClientData struct {
+    Type string                 `json:"type"`
+    Challenge string            `json:"challenge"`
+    Origin string               `json:"origin"`
+}
+
+type ecdsaSignature struct {
+    R, S *big.Int
+}
+
+// Credential public keys are COSE keys (RFC 8152, section 13); only ES256 and RS256 are supported
+func ParseCOSEKey(data []byte) (crypto.PublicKey, error) {
+    value, _, err := DecodeCBOR(data)
+    if err != nil {
+        return nil, err
+    }
+    key, ok := value.(map[interface{}]interface{})
+    if !ok {
+        return nil, errors.New("Invalid COSE key")
+    }
+    keyType, _ := key[coseKeyType].(int64)
+    algorithm, _ := key[coseKeyAlgorithm].(int64)
+    switch {
+    case keyType == coseKeyEC2 && algorithm == COSEAlgorithmES256:
+        curve, _ := key[int64(-1)].(int64)
+        x, _ := key[int64(-2)].([]byte)
+        y, _ := key[int64(-3)].([]byte)
+        if curve != coseCurveP256 || len(x) != es256CoordinateSize || len(y) != es256CoordinateSize {
+            return nil, errors.New("Invalid COSE key")
+        }
+        publicKey := &ecdsa.PublicKey{
+            Curve: elliptic.P256(),
+            X: new(big.Int).SetBytes(x),
+            Y: new(big.Int).SetBytes(y),
+        }
+        if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+            return nil, errors.New("Invalid COSE key")
+        }
+        return publicKey, nil
+    case keyType == coseKeyRSA && algorithm == COSEAlgorithmRS256:
+        n, _ := key[int64(-1)].([]byte)
+        e, _ := key[int64(-2)].([]byte)
+        if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+            return nil, errors.New("Invalid COSE key")
+        }
+        return &rsa.PublicKey{
+            N: new(big.Int).SetBytes(n),
+            E: int(new(big.Int).SetBytes(e).Int64()),
+        }, nil
+    }
+    return nil, errors.New("Unsupported COSE key")
+}
+
+// Authenticator data layout: WebAuthn Level 1, section 6.1
+func ParseAuthenticatorData(data []byte) (AuthenticatorData, error) {
+    var authenticatorData AuthenticatorData
+
+    if len(data) < authenticatorDataMinLength {
+        return AuthenticatorData{}, errors.New("Authenticator data too short")
+    }
+    authenticatorData.RPIDHash = data[:32]
+    authenticatorData.Flags = data[32]
+    authenticatorData.SignCount = binary.BigEndian.Uint32(data[33:37])
+    if authenticatorData.Flags & authenticatorAttestedData == 0 {
+        return authenticatorData, nil
+    }
+    // AAGUID (16 bytes) and the credential id length (2 bytes)
+    offset := authenticatorDataMinLength
+    if len(data) < offset + 18 {
+        return AuthenticatorData{}, errors.New("Authenticator data too short")
+    }
+    credentialIDLength := int(binary.BigEndian.Uint16(data[offset + 16:offset + 18]))
+    offset += 18
+    if len(data) < offset + credentialIDLength {
+        return AuthenticatorData{}, errors.New("Authenticator data too short")
+    }
+    authenticatorData.CredentialID = data[offset:offset + credentialIDLength]
+    offset += credentialIDLength
+    _, length, err := DecodeCBOR(data[offset:])
+    if err != nil {
+        return AuthenticatorData{}, err
+    }
+    authenticatorData.CredentialPublicKey = data[offset:offset + length]
+    return authenticatorData, nil
+}
+
+// The challenge a ceremony was run for, so the pending challenge is looked up; it's verified afterwards
+func WebAuthnChallenge(clientDataJSON []byte) (string, error) {
+    var clientData collectedClientData
+
+    if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+        return "", err
+    }
+    if clientData.Challenge == "" {
+        return "", errors.New("Missing challenge")
+    }
+    return clientData.Challenge, nil
+}
+
+func verifyClientData(clientDataJSON []byte, ceremony, challenge, origin string) error {
+    var clientData collectedClientData
+
+    if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+        return err
+    }
+    if clientData.Type != ceremony {
+        return errors.New("Unexpected ceremony type")
+    }
+    if challenge == "" || subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+        return errors.New("Unexpected challenge")
+    }
+    if clientData.Origin != origin {
+        return errors.New("Unexpected origin")
+    }
+    return nil
+}
+
+func verifyAuthenticatorData(authenticatorData AuthenticatorData, rpID string) error {
+    rpIDHash := sha256.Sum256([]byte(rpID))
+    if !bytes.Equal(authenticatorData.RPIDHash, rpIDHash[:]) {
+        return errors.New("Unexpected relying party")
+    }
+    if authenticatorData.Flags & authenticatorUserPresent == 0 {
+        return errors.New("User is not present")
+    }
+    return nil
+}
+
+// Registration ceremony (WebAuthn Level 1, section 7.1); the attestation statement is not verified,
+// since the `none` attestation conveyance is requested
+func VerifyWebAuthnRegistration(attestationObject, clientDataJSON []byte, challenge, origin, rpID string) (WebAuthnCredential, error) {
+    if err := verifyClientData(clientDataJSON, WebAuthnCreate, challenge, origin); err != nil {
+        return WebAuthnCredential{}, err
+    }
+    value, _, err := DecodeCBOR(attestationObject)
+    if err != nil {
+        return WebAuthnCredential{}, err
+    }
+    attestation, ok := value.(map[interface{}]interface{})
+    if !ok {
+        return WebAuthnCredential{}, errors.New("Invalid attestation object")
+    }
+    data, ok := attestation["authData"].([]byte)
+    if !ok {
+        return WebAuthnCredential{}, errors.New("Invalid attestation object")
+    }
+    authenticatorData, err := ParseAuthenticatorData(data)
+    if err != nil {
+        return WebAuthnCredential{}, err
+    }
+    if err := verifyAuthenticatorData(authenticatorData, rpID); err != nil {
+        return WebAuthnCredential{}, err
+    }
+    if len(authenticatorData.CredentialID) == 0 {
+        return WebAuthnCredential{}, errors.New("There's no attested credential")
+    }
+    if _, err := ParseCOSEKey(authenticatorData.CredentialPublicKey); err != nil {
+        return WebAuthnCredential{}, err
+    }
+    return WebAuthnCredential{
+        ID: authenticatorData.CredentialID,
+        PublicKey: authenticatorData.CredentialPublicKey,
+        SignCount: authenticatorData.SignCount,
+    }, nil
+}
+
+// Authentication ceremony (WebAuthn Level 1, section 7.2); it returns the new signature counter
+func VerifyWebAuthnAssertion(publicKey []byte, signCount uint32, authenticatorDataBytes, clientDataJSON, signature []byte, challenge, origin, rpID string) (uint32, error) {
+    if err := verifyClientData(clientDataJSON, WebAuthnGet, challenge, origin); err != nil {
+        return 0, err
+    }
+    authenticatorData, err := ParseAuthenticatorData(authenticatorDataBytes)
+    if err != nil {
+        return 0, err
+    }
+    if err := verifyAuthenticatorData(authenticatorData, rpID); err != nil {
+        return 0, err
+    }
+    key, err := ParseCOSEKey(publicKey)
+    if err != nil {
+        return 0, err
+    }
+    clientDataHash := sha256.Sum256(clientDataJSON)
+    signed := append(append([]byte(nil), authenticatorDataBytes...), clientDataHash[:]...)
+    digest := sha256.Sum256(signed)
+    switch verificationKey := key.(type) {
+    case *ecdsa.PublicKey:
+        var parsedSignature ecdsaSignature
+        if _, err := asn1.Unmarshal(signature, &parsedSignature); err != nil {
+            return 0, errors.New("Invalid signature")
+        }
+        if parsedSignature.R == nil || parsedSignature.S == nil ||
+                !ecdsa.Verify(verificationKey, digest[:], parsedSignature.R, parsedSignature.S) {
+            return 0, errors.New("Invalid signature")
+        }
+    case *rsa.PublicKey:
+        if err := rsa.VerifyPKCS1v15(verificationKey, crypto.SHA256, digest[:], signature); err != nil {
+            return 0, errors.New("Invalid signature")
+        }
+    default:
+        return 0, errors.New("Unsupported public key")
+    }
+    // A counter that doesn't increase may indicate a cloned authenticator
+    if (authenticatorData.SignCount != 0 || signCount != 0) && authenticatorData.SignCount <= signCount {
+        return 0, errors.New("Signature counter did not increase")
+    }
+    return authenticatorData.SignCount, nil
+}
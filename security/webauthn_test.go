@@ -0,0 +1,148 @@
+package security
+
+import (
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/json"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+const (
+    testRPID string = "space.example.com"
+    testOrigin string = "https://space.example.com"
+)
+
+// Software authenticator, used to exercise the registration and authentication ceremonies
+type softwareAuthenticator struct {
+    key *ecdsa.PrivateKey
+    credentialID []byte
+    signCount uint32
+}
+
+func cborByteString(data []byte) []byte {
+    if len(data) < 24 {
+        return append([]byte{0x40 | byte(len(data))}, data...)
+    }
+    if len(data) < 256 {
+        return append([]byte{0x58, byte(len(data))}, data...)
+    }
+    return append([]byte{0x59, byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func newSoftwareAuthenticator() *softwareAuthenticator {
+    key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    credentialID := make([]byte, 16)
+    rand.Read(credentialID)
+    return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (authenticator *softwareAuthenticator) coseKey() []byte {
+    coseKey := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21}
+    coseKey = append(coseKey, cborByteString(paddedBytes(authenticator.key.X, es256CoordinateSize))...)
+    coseKey = append(coseKey, 0x22)
+    return append(coseKey, cborByteString(paddedBytes(authenticator.key.Y, es256CoordinateSize))...)
+}
+
+func (authenticator *softwareAuthenticator) authenticatorData(rpID string, attested bool) []byte {
+    var flags byte = authenticatorUserPresent
+    rpIDHash := sha256.Sum256([]byte(rpID))
+    counter := make([]byte, 4)
+    authenticator.signCount++
+    binary.BigEndian.PutUint32(counter, authenticator.signCount)
+    if attested {
+        flags |= authenticatorAttestedData
+    }
+    data := append(append(rpIDHash[:], flags), counter...)
+    if attested {
+        data = append(data, make([]byte, 16)...)
+        data = append(data, byte(len(authenticator.credentialID) >> 8), byte(len(authenticator.credentialID)))
+        data = append(data, authenticator.credentialID...)
+        data = append(data, authenticator.coseKey()...)
+    }
+    return data
+}
+
+func clientDataJSON(ceremony, challenge, origin string) []byte {
+    data, _ := json.Marshal(collectedClientData{Type: ceremony, Challenge: challenge, Origin: origin})
+    return data
+}
+
+func (authenticator *softwareAuthenticator) create(challenge, origin, rpID string) ([]byte, []byte) {
+    attestationObject := []byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e',
+        0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0, 0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a'}
+    attestationObject = append(attestationObject, cborByteString(authenticator.authenticatorData(rpID, true))...)
+    return attestationObject, clientDataJSON(WebAuthnCreate, challenge, origin)
+}
+
+func (authenticator *softwareAuthenticator) get(challenge, origin, rpID string) ([]byte, []byte, []byte) {
+    authenticatorData := authenticator.authenticatorData(rpID, false)
+    clientData := clientDataJSON(WebAuthnGet, challenge, origin)
+    clientDataHash := sha256.Sum256(clientData)
+    digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientDataHash[:]...))
+    signature, _ := authenticator.key.Sign(rand.Reader, digest[:], nil)
+    return authenticatorData, clientData, signature
+}
+
+func TestWebAuthnCeremonies(t *testing.T) {
+    authenticator := newSoftwareAuthenticator()
+    challenge, _ := GenerateURLSafeToken(32)
+    attestationObject, clientData := authenticator.create(challenge, testOrigin, testRPID)
+    credential, err := VerifyWebAuthnRegistration(attestationObject, clientData, challenge, testOrigin, testRPID)
+    assert.Nil(t, err, "should have verified the registration")
+    assert.Equal(t, authenticator.credentialID, credential.ID, "should have extracted the credential id")
+    assert.Equal(t, uint32(1), credential.SignCount, "should have extracted the signature counter")
+
+    challenge, _ = GenerateURLSafeToken(32)
+    authenticatorData, clientData, signature := authenticator.get(challenge, testOrigin, testRPID)
+    signCount, err := VerifyWebAuthnAssertion(credential.PublicKey, credential.SignCount,
+        authenticatorData, clientData, signature, challenge, testOrigin, testRPID)
+    assert.Nil(t, err, "should have verified the assertion")
+    assert.Equal(t, uint32(2), signCount, "should have increased the signature counter")
+
+    _, err = VerifyWebAuthnAssertion(credential.PublicKey, signCount,
+        authenticatorData, clientData, signature, challenge, testOrigin, testRPID)
+    assert.NotNil(t, err, "should have refused a replayed assertion")
+}
+
+func TestWebAuthnRefusesPhishingOrigins(t *testing.T) {
+    authenticator := newSoftwareAuthenticator()
+    challenge, _ := GenerateURLSafeToken(32)
+    attestationObject, clientData := authenticator.create(challenge, "https://space.example.co", testRPID)
+    _, err := VerifyWebAuthnRegistration(attestationObject, clientData, challenge, testOrigin, testRPID)
+    assert.NotNil(t, err, "should have refused another origin")
+    attestationObject, clientData = authenticator.create(challenge, testOrigin, "example.co")
+    _, err = VerifyWebAuthnRegistration(attestationObject, clientData, challenge, testOrigin, testRPID)
+    assert.NotNil(t, err, "should have refused another relying party")
+    attestationObject, clientData = authenticator.create(challenge, testOrigin, testRPID)
+    _, err = VerifyWebAuthnRegistration(attestationObject, clientData, "another-challenge", testOrigin, testRPID)
+    assert.NotNil(t, err, "should have refused another challenge")
+}
+
+func TestWebAuthnRefusesInvalidSignatures(t *testing.T) {
+    authenticator := newSoftwareAuthenticator()
+    anotherAuthenticator := newSoftwareAuthenticator()
+    challenge, _ := GenerateURLSafeToken(32)
+    attestationObject, clientData := authenticator.create(challenge, testOrigin, testRPID)
+    credential, _ := VerifyWebAuthnRegistration(attestationObject, clientData, challenge, testOrigin, testRPID)
+    anotherAuthenticator.signCount = authenticator.signCount
+    authenticatorData, clientData, signature := anotherAuthenticator.get(challenge, testOrigin, testRPID)
+    _, err := VerifyWebAuthnAssertion(credential.PublicKey, credential.SignCount,
+        authenticatorData, clientData, signature, challenge, testOrigin, testRPID)
+    assert.NotNil(t, err, "should have refused a signature from another key")
+}
+
+func TestWebAuthnChallenge(t *testing.T) {
+    challenge, _ := GenerateURLSafeToken(32)
+    extracted, err := WebAuthnChallenge(clientDataJSON(WebAuthnGet, challenge, testOrigin))
+    assert.Nil(t, err, "should have extracted the challenge")
+    assert.Equal(t, challenge, extracted, "should have extracted the challenge")
+    _, err = WebAuthnChallenge(clientDataJSON(WebAuthnGet, "", testOrigin))
+    assert.NotNil(t, err, "should have refused a missing challenge")
+    _, err = WebAuthnChallenge([]byte("{"))
+    assert.NotNil(t, err, "should have refused invalid client data")
+}
@@ -1,11 +1,11 @@
 package services
 
 import (
-    "github.com/earaujoassis/space/datastore"
     "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/security"
 )
 
-func CreateNewClient(name, description, secret, scopes, canonicalURI, redirectURI string) models.Client {
+func CreateNewClient(name, description, secret, scopes, canonicalURI, redirectURI, clientType, accessTokenFormat string) models.Client {
     var client models.Client = models.Client{
         Name: name,
         Description: description,
@@ -13,68 +13,59 @@ func CreateNewClient(name, description, secret, scopes, canonicalURI, redirectUR
         Scopes: scopes,
         CanonicalURI: canonicalURI,
         RedirectURI: redirectURI,
-        Type: models.ConfidentialClient,
+        Type: clientType,
+        AccessTokenFormat: accessTokenFormat,
     }
 
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Create(&client)
+    clientRepository().Create(&client)
     return client
 }
 
 func FindOrCreateClient(name string) models.Client {
     var client models.Client
 
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Where("name = ?", name).First(&client)
-    if dataStoreSession.NewRecord(client) {
+    client = clientRepository().FindByName(name)
+    if client.ID == 0 {
+        secret, err := security.GenerateToken(64)
+        if err != nil {
+            return models.Client{}
+        }
         client = models.Client{
             Name: name,
-            Secret: models.GenerateRandomString(64),
+            Secret: secret,
             CanonicalURI: "localhost",
             RedirectURI: "/",
             Scopes: models.PublicScope,
             Type: models.PublicClient,
         }
-        dataStoreSession.Create(&client)
+        clientRepository().Create(&client)
     }
     return client
 }
 
 func FindClientByKey(key string) models.Client {
-    var client models.Client
-
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Where("key = ?", key).First(&client)
-    return client
+    return clientRepository().FindByKey(key)
 }
 
 func FindClientByUUID(uuid string) models.Client {
-    var client models.Client
-
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Where("uuid = ?", uuid).First(&client)
-    return client
+    return clientRepository().FindByUUID(uuid)
 }
 
 func ClientAuthentication(key, secret string) models.Client {
     var client models.Client
 
     client = FindClientByKey(key)
+    storedSecret := client.Secret
     if client.ID != 0 && client.Authentic(secret) {
+        // Weaker hashes are upgraded on a successful authentication
+        if client.Secret != storedSecret {
+            clientRepository().SaveSecret(client)
+        }
         return client
     }
     return models.Client{}
 }
 
 func ActiveClientsForUser(userIID uint) []models.Client {
-    var clients []models.Client
-
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.
-        Raw("SELECT DISTINCT clients.uuid, clients.name, clients.description, clients.canonical_uri " +
-            "FROM clients JOIN sessions ON clients.id = sessions.client_id " +
-            "WHERE sessions.token_type IN ('access_token', 'refresh_token') AND sessions.invalidated = false AND " +
-            "sessions.user_id = ?;", userIID).
-        Scan(&clients)
-    return clients
+    return clientRepository().ActiveForUser(userIID)
 }
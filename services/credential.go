@@ -0,0 +1,74 @@
+package services
+
+import (
+    "time"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/security"
+)
+
+func CreateCredential(user models.User, webAuthnCredential security.WebAuthnCredential, transports, name string) models.Credential {
+    var credential models.Credential = models.Credential{
+        User: user,
+        CredentialID: models.EncodeCredentialData(webAuthnCredential.ID),
+        PublicKey: models.EncodeCredentialData(webAuthnCredential.PublicKey),
+        SignCount: int64(webAuthnCredential.SignCount),
+        Transports: transports,
+        Name: name,
+    }
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Create(&credential)
+    return credential
+}
+
+func CredentialsForUser(userIID uint) []models.Credential {
+    var credentials []models.Credential
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("user_id = ?", userIID).Order("created_at asc").Find(&credentials)
+    return credentials
+}
+
+func FindCredentialForUser(userIID uint, credentialID string) models.Credential {
+    var credential models.Credential
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("user_id = ? AND credential_id = ?", userIID, credentialID).First(&credential)
+    return credential
+}
+
+func DeleteCredential(userIID uint, uuid string) {
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("user_id = ? AND uuid = ?", userIID, uuid).Delete(models.Credential{})
+}
+
+// Admins who registered a security key must use it to sign in (phishing-resistant second factor)
+func SecurityKeyRequired(user models.User) bool {
+    return user.Admin && len(CredentialsForUser(user.ID)) > 0
+}
+
+// Verifies an assertion against the pending authentication challenge of the user
+func AuthenticSecurityKey(user models.User, credentialID string, authenticatorData, clientDataJSON, signature []byte, origin, rpID string) bool {
+    challenge, err := security.WebAuthnChallenge(clientDataJSON)
+    if err != nil || !models.ConsumeWebAuthnChallenge(models.AuthenticationCeremony, user.UUID, challenge) {
+        return false
+    }
+    credential := FindCredentialForUser(user.ID, credentialID)
+    if credential.ID == 0 {
+        return false
+    }
+    publicKey, err := credential.PublicKeyData()
+    if err != nil {
+        return false
+    }
+    signCount, err := security.VerifyWebAuthnAssertion(publicKey, uint32(credential.SignCount),
+        authenticatorData, clientDataJSON, signature, challenge, origin, rpID)
+    if err != nil {
+        return false
+    }
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Model(&credential).UpdateColumns(map[string]interface{}{
+        "sign_count": int64(signCount),
+        "last_used_at": time.Now().UTC(),
+    })
+    return true
+}
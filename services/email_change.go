@@ -0,0 +1,25 @@
+package services
+
+import (
+    "github.com/earaujoassis/space/models"
+)
+
+func CreateEmailChange(user models.User, email string) models.EmailChange {
+    var change models.EmailChange = models.EmailChange{
+        UserID: user.ID,
+        Email: email,
+    }
+    if err := change.Save(); err != nil {
+        return models.EmailChange{}
+    }
+    return change
+}
+
+func FindEmailChangeByToken(token string) models.EmailChange {
+    var change models.EmailChange = models.RetrieveEmailChangeByToken(token)
+    if change.UUID != "" && !change.WithinExpirationWindow() {
+        change.Delete()
+        return models.EmailChange{}
+    }
+    return change
+}
@@ -15,3 +15,10 @@ func FindOrCreateLanguage(name, isoCode string) models.Language {
     }
     return language
 }
+
+func FindLanguageByIsoCode(isoCode string) models.Language {
+    var language models.Language
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("iso_code = ?", isoCode).First(&language)
+    return language
+}
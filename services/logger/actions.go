@@ -16,10 +16,62 @@ func LogAction(name string, data utils.H) {
             data["Year"] = time.Now().Year()
             message := mailer.CreateMessage("user.created.html", data)
             mailer.SendEmail("Welcome to QuatroLabs services", message, data["Email"].(string))
+        case "user.confirmation":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.confirm.html", data)
+            mailer.SendEmail("Confirm your email at QuatroLabs", message, data["Email"].(string))
         case "session.created":
             data["Year"] = time.Now().Year()
             message := mailer.CreateMessage("session.created.html", data)
             mailer.SendEmail("A new session created at QuatroLabs", message, data["Email"].(string))
+        case "user.email.confirmation":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.email.confirmation.html", data)
+            mailer.SendEmail("Confirm your new email at QuatroLabs", message, data["Email"].(string))
+        case "user.email.changed":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.email.changed.html", data)
+            mailer.SendEmail("Your email was changed at QuatroLabs", message, data["Email"].(string))
+        case "user.deactivated":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.deactivated.html", data)
+            mailer.SendEmail("Your account was deactivated at QuatroLabs", message, data["Email"].(string))
+        case "user.recovered":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.recovered.html", data)
+            mailer.SendEmail("Your account was recovered at QuatroLabs", message, data["Email"].(string))
+        case "user.recovery.failed":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.recovery.failed.html", data)
+            mailer.SendEmail("An attempt to recover your account at QuatroLabs", message, data["Email"].(string))
+        case "user.password.forgot":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.password.forgot.html", data)
+            mailer.SendEmail("Reset your password at QuatroLabs", message, data["Email"].(string))
+        case "user.password.reset":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.password.reset.html", data)
+            mailer.SendEmail("Your password was reset at QuatroLabs", message, data["Email"].(string))
+        case "user.password.changed":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.password.changed.html", data)
+            mailer.SendEmail("Your password was changed at QuatroLabs", message, data["Email"].(string))
+        case "user.credential.created":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.credential.created.html", data)
+            mailer.SendEmail("A security key was added at QuatroLabs", message, data["Email"].(string))
+        case "user.credential.deleted":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.credential.deleted.html", data)
+            mailer.SendEmail("A security key was removed at QuatroLabs", message, data["Email"].(string))
+        case "user.device.created":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.device.created.html", data)
+            mailer.SendEmail("An authenticator was added at QuatroLabs", message, data["Email"].(string))
+        case "user.device.deleted":
+            data["Year"] = time.Now().Year()
+            message := mailer.CreateMessage("user.device.deleted.html", data)
+            mailer.SendEmail("An authenticator was removed at QuatroLabs", message, data["Email"].(string))
         }
     }
     if config.IsEnvironment("development") {
@@ -0,0 +1,26 @@
+package services
+
+import (
+    "github.com/earaujoassis/space/models"
+)
+
+func CreatePasswordReset(user models.User, ip, userAgent string) models.PasswordReset {
+    var reset models.PasswordReset = models.PasswordReset{
+        User: user,
+        Ip: ip,
+        UserAgent: userAgent,
+    }
+    if err := reset.Save(); err != nil {
+        return models.PasswordReset{}
+    }
+    return reset
+}
+
+func FindPasswordResetByToken(token string) models.PasswordReset {
+    var reset models.PasswordReset = models.RetrievePasswordResetByToken(token)
+    if reset.UUID != "" && !reset.WithinExpirationWindow() {
+        reset.Delete()
+        return models.PasswordReset{}
+    }
+    return reset
+}
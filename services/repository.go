@@ -0,0 +1,41 @@
+package services
+
+import (
+    "sync"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/repository"
+)
+
+var repositories *repository.Repositories
+var repositoriesMutex sync.Mutex
+
+// Users, clients and sessions are stored through repositories; unless others are injected
+// with UseRepositories (e.g. in-memory ones, for testing), they're backed by the data store
+func UseRepositories(injected repository.Repositories) {
+    repositoriesMutex.Lock()
+    defer repositoriesMutex.Unlock()
+    repositories = &injected
+}
+
+func currentRepositories() repository.Repositories {
+    repositoriesMutex.Lock()
+    defer repositoriesMutex.Unlock()
+    if repositories == nil {
+        gormRepositories := repository.NewGormRepositories(datastore.GetDataStoreConnection())
+        repositories = &gormRepositories
+    }
+    return *repositories
+}
+
+func userRepository() repository.UserRepository {
+    return currentRepositories().Users
+}
+
+func clientRepository() repository.ClientRepository {
+    return currentRepositories().Clients
+}
+
+func sessionRepository() repository.SessionRepository {
+    return currentRepositories().Sessions
+}
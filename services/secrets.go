@@ -0,0 +1,63 @@
+package services
+
+import (
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/models"
+)
+
+// Re-encrypts the stored secrets (TOTP code secrets and signing keys) with the primary key;
+// it returns how many secrets were changed, and stops at the first error
+func ReencryptSecrets() (int, error) {
+    var users []models.User
+    var devices []models.TOTPDevice
+    var keys []models.SigningKey
+    var count int
+
+    dataStoreSession := datastore.GetDataStoreConnection()
+    if err := dataStoreSession.Select("id, code_secret").Find(&users).Error; err != nil {
+        return count, err
+    }
+    for _, user := range users {
+        changed, err := user.ReencryptCodeSecret()
+        if err != nil {
+            return count, err
+        }
+        if changed {
+            if err := dataStoreSession.Model(&user).UpdateColumn("code_secret", user.CodeSecret).Error; err != nil {
+                return count, err
+            }
+            count++
+        }
+    }
+    if err := dataStoreSession.Select("id, code_secret").Find(&devices).Error; err != nil {
+        return count, err
+    }
+    for _, device := range devices {
+        changed, err := device.ReencryptCodeSecret()
+        if err != nil {
+            return count, err
+        }
+        if changed {
+            if err := dataStoreSession.Model(&device).UpdateColumn("code_secret", device.CodeSecret).Error; err != nil {
+                return count, err
+            }
+            count++
+        }
+    }
+    if err := dataStoreSession.Find(&keys).Error; err != nil {
+        return count, err
+    }
+    for _, key := range keys {
+        changed, err := key.ReencryptPrivateKey()
+        if err != nil {
+            return count, err
+        }
+        if changed {
+            if err := dataStoreSession.Model(&key).UpdateColumn("private_key", key.PrivateKey).Error; err != nil {
+                return count, err
+            }
+            count++
+        }
+    }
+    return count, nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/repository"
+)
+
+func TestReencryptSecretsSkipsAnonymizedUsers(t *testing.T) {
+    setUpDataStore(t)
+    UseRepositories(repository.NewGormRepositories(datastore.GetDataStoreConnection()))
+    user := models.User{
+        FirstName: "Jane",
+        LastName: "Doe",
+        Username: "janedoe",
+        Email: "jane@saturn.com",
+        Passphrase: "correct horse battery staple",
+        Active: true,
+        Client: FindOrCreateClient("Jupiter"),
+        Language: models.Language{Name: "English", IsoCode: "en-US"},
+    }
+    user.GenerateCodeSecret()
+    user.GenerateRecoverSecret()
+    assert.Nil(t, CreateUser(&user), "should create a user")
+    assert.Nil(t, userRepository().Anonymize(user), "should anonymize the user")
+    _, err := RotateSigningKeys("ES256")
+    assert.Nil(t, err, "should create the signing keys")
+
+    count, err := ReencryptSecrets()
+    assert.Nil(t, err, "should skip the empty secret of an anonymized user")
+    assert.Equal(t, 0, count, "should not re-encrypt secrets already under the primary key")
+}
@@ -1,45 +1,65 @@
 package services
 
 import (
-    "github.com/earaujoassis/space/datastore"
     "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/repository"
 )
 
 func CreateSession(user models.User, client models.Client, ip, userAgent, scopes, tokenType string) models.Session {
-    var session models.Session = models.Session{
+    return saveSession(models.Session{
         User: user,
         Client: client,
         Ip: ip,
         UserAgent: userAgent,
         Scopes: scopes,
         TokenType: tokenType,
+    })
+}
+
+// Sessions created together (e.g. an access token issued with a refresh token) are linked through ParentID
+func CreateChildSession(parent models.Session, tokenType string) models.Session {
+    return saveSession(models.Session{
+        User: parent.User,
+        Client: parent.Client,
+        Ip: parent.Ip,
+        UserAgent: parent.UserAgent,
+        Scopes: parent.Scopes,
+        TokenType: tokenType,
+        ParentID: parent.ID,
+    })
+}
+
+func CreateGrantSession(user models.User, client models.Client, ip, userAgent, scopes, codeChallenge, codeChallengeMethod, nonce string) models.Session {
+    return saveSession(models.Session{
+        User: user,
+        Client: client,
+        Ip: ip,
+        UserAgent: userAgent,
+        Scopes: scopes,
+        TokenType: models.GrantToken,
+        CodeChallenge: codeChallenge,
+        CodeChallengeMethod: codeChallengeMethod,
+        Nonce: nonce,
+    })
+}
+
+func saveSession(session models.Session) models.Session {
+    if err := sessionRepository().Create(&session); err != nil {
+        return models.Session{}
     }
-    dataStore := datastore.GetDataStoreConnection()
-    result := dataStore.Create(&session)
-    if count := result.RowsAffected; count > 0 {
-        return session
-    }
-    return models.Session{}
+    return session
 }
 
 func SessionGrantsReadAbility(session models.Session) bool {
-    return session.Scopes == models.ReadScope || session.Scopes == models.ReadWriteScope
+    return models.ScopeIncludes(session.Scopes, models.ReadScope) || models.ScopeIncludes(session.Scopes, models.ReadWriteScope)
 }
 
 func SessionGrantsWriteAbility(session models.Session) bool {
-    return session.Scopes == models.ReadWriteScope
+    return models.ScopeIncludes(session.Scopes, models.ReadWriteScope)
 }
 
-func FindSessionByUUID(uuid string) models.Session {
-    var session models.Session
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.
-        Preload("Client").
-        Preload("User").
-        Preload("User.Client").
-        Preload("User.Language").
-        Where("uuid = ? AND invalidated = false", uuid).
-        First(&session)
+// Expired sessions are invalidated once they're found
+func validSession(session models.Session) models.Session {
     if session.ID != 0 {
         if !session.WithinExpirationWindow() {
             InvalidateSession(session)
@@ -49,48 +69,57 @@ func FindSessionByUUID(uuid string) models.Session {
     return session
 }
 
+func FindSessionByUUID(uuid string) models.Session {
+    return validSession(sessionRepository().FindByUUID(uuid))
+}
+
 func FindSessionByToken(token, tokenType string) models.Session {
-    var session models.Session
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.
-        Preload("Client").
-        Preload("User").
-        Preload("User.Client").
-        Preload("User.Language").
-        Where("token = ? AND token_type = ? AND invalidated = false", token, tokenType).
-        First(&session)
-    if session.ID != 0 {
-        if !session.WithinExpirationWindow() {
-            InvalidateSession(session)
-            return models.Session{}
-        }
-    }
-    return session
+    return validSession(sessionRepository().FindByToken(token, tokenType))
 }
 
 func InvalidateSession(session models.Session) {
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Model(&session).Select("invalidated").Update("invalidated", true)
+    sessionRepository().Invalidate(session)
+    if session.TokenType == models.AccessToken {
+        models.RevokeAccessToken(session.UUID, session.Moment + session.ExpiresIn)
+    }
 }
 
-func ActiveSessionsForClient(clientIID, userIID uint) int64 {
-    var count struct{
-        Count int64
+// Access tokens invalidated in bulk must also be added to the revocation list
+func revokeSessions(filter repository.SessionFilter) {
+    sessions, _ := sessionRepository().InvalidateWhere(filter)
+    for _, session := range sessions {
+        if session.TokenType == models.AccessToken {
+            models.RevokeAccessToken(session.UUID, session.Moment + session.ExpiresIn)
+        }
     }
+}
 
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.
-        Raw("SELECT count(*) AS count " +
-            "FROM sessions WHERE token_type IN ('access_token', 'refresh_token') AND invalidated = false AND " +
-            "client_id = ? AND user_id = ?;", clientIID, userIID).
-        Scan(&count)
-    return count.Count
+// Revoking a refresh token also invalidates the access tokens issued with it (RFC 7009, section 2.1)
+func RevokeSession(session models.Session) {
+    InvalidateSession(session)
+    if session.TokenType != models.RefreshToken {
+        return
+    }
+    revokeSessions(repository.SessionFilter{ParentID: session.ID})
 }
 
+func ActiveSessionsForClient(clientIID, userIID uint) int64 {
+    return sessionRepository().CountActive(clientIID, userIID)
+}
+
+// A zero ID would match every client (or user) in the filter; there's nothing to revoke for it
 func RevokeClientAccess(clientIID, userIID uint) {
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.
-        Exec("UPDATE sessions SET invalidated = true, updated_at = now() " +
-            "WHERE token_type IN ('access_token', 'refresh_token') AND invalidated = false AND " +
-            "client_id = ? AND user_id = ?;", clientIID, userIID)
+    if clientIID == 0 || userIID == 0 {
+        return
+    }
+    revokeSessions(repository.SessionFilter{
+        UserID: userIID,
+        ClientID: clientIID,
+        TokenTypes: []string{models.AccessToken, models.RefreshToken},
+    })
+}
+
+// Every session of the user is revoked, for all clients and token types
+func RevokeUserAccess(userIID uint) {
+    revokeSessions(repository.SessionFilter{UserID: userIID})
 }
@@ -0,0 +1,24 @@
+package services
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/models"
+)
+
+func TestRevokeClientAccess(t *testing.T) {
+    user := createTestingUser(t)
+    other := FindOrCreateClient("Saturn")
+    refreshToken := CreateSession(user, user.Client, "127.0.0.1", "Testing", models.ReadScope, models.RefreshToken)
+    CreateChildSession(refreshToken, models.AccessToken)
+    CreateSession(user, other, "127.0.0.1", "Testing", models.ReadScope, models.RefreshToken)
+
+    RevokeClientAccess(FindClientByUUID("00000000-0000-0000-0000-000000000000").ID, user.ID)
+    assert.Equal(t, int64(2), ActiveSessionsForClient(user.Client.ID, user.ID), "should not revoke anything for an unknown client")
+    assert.Equal(t, int64(1), ActiveSessionsForClient(other.ID, user.ID), "should not revoke anything for an unknown client")
+    RevokeClientAccess(user.Client.ID, user.ID)
+    assert.Equal(t, int64(0), ActiveSessionsForClient(user.Client.ID, user.ID), "should revoke the sessions for the client")
+    assert.Equal(t, int64(1), ActiveSessionsForClient(other.ID, user.ID), "should keep the sessions for other clients")
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+    "time"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/models"
+)
+
+func ActiveSigningKey() models.SigningKey {
+    var key models.SigningKey
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("status = ?", models.ActiveKey).Order("created_at desc").First(&key)
+    return key
+}
+
+// Active, next and retired keys are all published, so resource servers are able to
+// verify tokens signed before and right after a rotation
+func PublishedSigningKeys() []models.SigningKey {
+    var keys []models.SigningKey
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Order("created_at desc").Find(&keys)
+    return keys
+}
+
+// Rotation drops the keys retired for longer than the tokens they signed may last, retires the
+// active key, activates the next key and creates a new next key; the first rotation creates both
+// the active and next keys
+func RotateSigningKeys(algorithm string) (models.SigningKey, error) {
+    var activeCount int
+
+    now := time.Now().UTC()
+    retiredBefore := now.Add(-time.Duration(models.RetiredKeyLifetime) * time.Second)
+    dataStoreSession := datastore.GetDataStoreConnection()
+    transaction := dataStoreSession.Begin()
+    err := transaction.Where("status = ? AND updated_at < ?", models.RetiredKey, retiredBefore).
+        Delete(models.SigningKey{}).Error
+    if err != nil {
+        transaction.Rollback()
+        return models.SigningKey{}, err
+    }
+    err = transaction.Model(models.SigningKey{}).Where("status = ?", models.ActiveKey).
+        UpdateColumns(map[string]interface{}{"status": models.RetiredKey, "updated_at": now}).Error
+    if err != nil {
+        transaction.Rollback()
+        return models.SigningKey{}, err
+    }
+    err = transaction.Model(models.SigningKey{}).Where("status = ?", models.NextKey).
+        UpdateColumns(map[string]interface{}{"status": models.ActiveKey, "updated_at": now}).Error
+    if err != nil {
+        transaction.Rollback()
+        return models.SigningKey{}, err
+    }
+    if err := transaction.Model(models.SigningKey{}).Where("status = ?", models.ActiveKey).Count(&activeCount).Error; err != nil {
+        transaction.Rollback()
+        return models.SigningKey{}, err
+    }
+    if activeCount == 0 {
+        activeKey, err := models.GenerateSigningKey(algorithm, models.ActiveKey)
+        if err != nil {
+            transaction.Rollback()
+            return models.SigningKey{}, err
+        }
+        if err := transaction.Create(&activeKey).Error; err != nil {
+            transaction.Rollback()
+            return models.SigningKey{}, err
+        }
+    }
+    nextKey, err := models.GenerateSigningKey(algorithm, models.NextKey)
+    if err != nil {
+        transaction.Rollback()
+        return models.SigningKey{}, err
+    }
+    if err := transaction.Create(&nextKey).Error; err != nil {
+        transaction.Rollback()
+        return models.SigningKey{}, err
+    }
+    if err := transaction.Commit().Error; err != nil {
+        return models.SigningKey{}, err
+    }
+    return ActiveSigningKey(), nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+    "os"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/models"
+)
+
+// Signing keys and secrets are kept in the data store only; they're tested against an in-memory SQLite database
+func setUpDataStore(t *testing.T) {
+    os.Setenv("SPACE_STORAGE_SECRET", "Mx2kvQ9sT4bN7pLw3eRz8yUc5aHf6jDg")
+    os.Setenv("SPACE_DATASTORE_DRIVER", datastore.SQLiteDriver)
+    os.Setenv("SPACE_DATASTORE_PATH", ":memory:")
+    if _, err := datastore.Migrate(); err != nil {
+        t.Fatal(err)
+    }
+    dataStore := datastore.GetDataStoreConnection()
+    dataStore.Delete(models.SigningKey{})
+    dataStore.Delete(models.Session{})
+    dataStore.Delete(models.User{})
+    dataStore.Delete(models.Client{})
+    dataStore.Delete(models.Language{})
+}
+
+func TestRotateSigningKeys(t *testing.T) {
+    var retiredCount int
+
+    setUpDataStore(t)
+    first, err := RotateSigningKeys("ES256")
+    assert.Nil(t, err, "should create the first keys")
+    assert.NotEqual(t, uint(0), first.ID, "should create an active key")
+    _, err = RotateSigningKeys("ES256")
+    assert.Nil(t, err, "should rotate the keys")
+    _, err = RotateSigningKeys("ES256")
+    assert.Nil(t, err, "should rotate the keys again")
+
+    dataStore := datastore.GetDataStoreConnection()
+    dataStore.Model(models.SigningKey{}).Where("status = ?", models.RetiredKey).Count(&retiredCount)
+    assert.Equal(t, 2, retiredCount, "should keep keys retired while their tokens may be valid")
+    assert.Equal(t, 4, len(PublishedSigningKeys()), "should publish the retired keys")
+
+    retiredAt := time.Now().UTC().Add(-time.Duration(models.RetiredKeyLifetime + 60) * time.Second)
+    dataStore.Model(models.SigningKey{}).Where("id = ?", first.ID).UpdateColumn("updated_at", retiredAt)
+    _, err = RotateSigningKeys("ES256")
+    assert.Nil(t, err, "should rotate the keys")
+    for _, key := range PublishedSigningKeys() {
+        assert.NotEqual(t, first.Kid, key.Kid, "should drop a key retired for longer than the token lifetime")
+    }
+    dataStore.Model(models.SigningKey{}).Where("status = ?", models.RetiredKey).Count(&retiredCount)
+    assert.Equal(t, 2, retiredCount, "should keep the recently retired keys")
+}
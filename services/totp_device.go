@@ -0,0 +1,46 @@
+package services
+
+import (
+    "time"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/models"
+)
+
+func CreateTOTPDevice(device models.TOTPDevice) models.TOTPDevice {
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Create(&device)
+    return device
+}
+
+func TOTPDevicesForUser(userIID uint) []models.TOTPDevice {
+    var devices []models.TOTPDevice
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("user_id = ?", userIID).Order("created_at asc").Find(&devices)
+    return devices
+}
+
+func DeleteTOTPDevice(userIID uint, uuid string) {
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("user_id = ? AND uuid = ?", userIID, uuid).Delete(models.TOTPDevice{})
+}
+
+func DeleteTOTPDevicesForUser(userIID uint) {
+    dataStoreSession := datastore.GetDataStoreConnection()
+    dataStoreSession.Where("user_id = ?", userIID).Delete(models.TOTPDevice{})
+}
+
+// Passcodes are accepted from the primary authenticator or from any enrolled TOTP device
+func AuthenticPasscode(user models.User, passcode string) bool {
+    if user.AuthenticPasscode(passcode) {
+        return true
+    }
+    for _, device := range TOTPDevicesForUser(user.ID) {
+        if device.AuthenticPasscode(passcode) {
+            dataStoreSession := datastore.GetDataStoreConnection()
+            dataStoreSession.Model(&device).UpdateColumn("last_used_at", time.Now().UTC())
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/pquerna/otp/totp"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticPasscodeRefusesConcurrentReplays(t *testing.T) {
+    var group sync.WaitGroup
+    var acceptedMutex sync.Mutex
+    var accepted int
+
+    setUpDataStore(t)
+    user := createTestingUser(t)
+    codeSecretKey := user.GenerateCodeSecret()
+    passcode, _ := totp.GenerateCode(codeSecretKey.Secret(), time.Now().UTC())
+    for i := 0; i < 20; i++ {
+        group.Add(1)
+        go func() {
+            defer group.Done()
+            if AuthenticPasscode(user, passcode) {
+                acceptedMutex.Lock()
+                accepted++
+                acceptedMutex.Unlock()
+            }
+        }()
+    }
+    group.Wait()
+    assert.Equal(t, 1, accepted, "should accept a passcode only once")
+}
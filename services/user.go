@@ -1,27 +1,93 @@
 package services
 
 import (
-    "github.com/earaujoassis/space/datastore"
+    "strconv"
+    "time"
+
+    "github.com/earaujoassis/space/config"
     "github.com/earaujoassis/space/models"
 )
 
+const (
+    defaultDeactivationGracePeriod int = 30 // days
+)
+
 func FindUserByAccountHolder(holder string) models.User {
-    var user models.User
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Preload("Client").Preload("Language").Where("username = ? OR email = ?", holder, holder).First(&user)
-    return user
+    return userRepository().FindByAccountHolder(holder)
 }
 
 func FindUserByPublicId(publicId string) models.User {
-    var user models.User
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Preload("Client").Preload("Language").Where("public_id = ?", publicId).First(&user)
-    return user
+    return userRepository().FindByPublicId(publicId)
 }
 
 func FindUserByUUID(uuid string) models.User {
-    var user models.User
-    dataStoreSession := datastore.GetDataStoreConnection()
-    dataStoreSession.Preload("Client").Preload("Language").Where("uuid = ?", uuid).First(&user)
-    return user
+    return userRepository().FindByUUID(uuid)
+}
+
+func FindUserByID(id uint) models.User {
+    return userRepository().FindByID(id)
+}
+
+// The client and the language are stored along with a new user
+func CreateUser(user *models.User) error {
+    return userRepository().Create(user)
+}
+
+// Associations (client and language) are not saved along with the user
+func SaveUser(user *models.User) error {
+    return userRepository().Save(user)
+}
+
+// Weaker passphrase hashes are upgraded on a successful authentication
+func AuthenticPassword(user *models.User, password string) bool {
+    storedPassphrase := user.Passphrase
+    if !user.AuthenticPassword(password) {
+        return false
+    }
+    if user.Passphrase != storedPassphrase {
+        userRepository().SavePassphrase(*user)
+    }
+    return true
+}
+
+// Deactivated users are unable to sign in and all their sessions are revoked
+func DeactivateUser(user *models.User) error {
+    user.Deactivate()
+    if err := SaveUser(user); err != nil {
+        return err
+    }
+    RevokeUserAccess(user.ID)
+    return nil
+}
+
+// Days after the deactivation until the user is anonymized (SPACE_DEACTIVATION_GRACE_DAYS)
+func DeactivationGracePeriod() int {
+    if days, err := strconv.Atoi(config.GetConfig("SPACE_DEACTIVATION_GRACE_DAYS")); err == nil && days >= 0 {
+        return days
+    }
+    return defaultDeactivationGracePeriod
+}
+
+func UsersDeactivatedBefore(moment time.Time) []models.User {
+    return userRepository().DeactivatedBefore(moment)
+}
+
+// Personally identifiable information is replaced in the user and in their sessions;
+// the rows are kept, so relationships with other records remain valid
+func AnonymizeUser(user models.User) error {
+    return userRepository().Anonymize(user)
+}
+
+// Users deactivated for longer than the grace period are anonymized; it returns how many were anonymized
+func PurgeDeactivatedUsers() (int, error) {
+    var count int
+
+    moment := time.Now().UTC().AddDate(0, 0, -DeactivationGracePeriod())
+    for _, user := range UsersDeactivatedBefore(moment) {
+        if err := AnonymizeUser(user); err != nil {
+            return count, err
+        }
+        count++
+    }
+    return count, nil
 }
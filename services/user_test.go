@@ -0,0 +1,63 @@
+package services
+
+import (
+    "os"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/repository"
+)
+
+func createTestingUser(t *testing.T) models.User {
+    os.Setenv("SPACE_STORAGE_SECRET", "Mx2kvQ9sT4bN7pLw3eRz8yUc5aHf6jDg")
+    memstore.Use(memstore.NewMemoryStore())
+    UseRepositories(repository.NewMemoryRepositories())
+    user := models.User{
+        FirstName: "Jane",
+        LastName: "Doe",
+        Username: "janedoe",
+        Email: "jane@saturn.com",
+        Passphrase: "correct horse battery staple",
+        Active: true,
+        Client: FindOrCreateClient("Jupiter"),
+        Language: models.Language{Name: "English", IsoCode: "en-US"},
+    }
+    user.GenerateCodeSecret()
+    user.GenerateRecoverSecret()
+    assert.Nil(t, CreateUser(&user), "should create a user")
+    return user
+}
+
+func TestDeactivateUser(t *testing.T) {
+    user := createTestingUser(t)
+    refreshToken := CreateSession(user, user.Client, "127.0.0.1", "Testing", models.ReadScope, models.RefreshToken)
+    accessToken := CreateChildSession(refreshToken, models.AccessToken)
+
+    assert.Equal(t, int64(2), ActiveSessionsForClient(user.Client.ID, user.ID), "should count the active sessions")
+    assert.Nil(t, DeactivateUser(&user), "should deactivate the user")
+    assert.False(t, FindUserByID(user.ID).Active, "should store the deactivation")
+    assert.Equal(t, int64(0), ActiveSessionsForClient(user.Client.ID, user.ID), "should revoke every session")
+    assert.True(t, models.AccessTokenRevoked(accessToken.UUID), "should add the access tokens to the revocation list")
+}
+
+func TestPurgeDeactivatedUsers(t *testing.T) {
+    user := createTestingUser(t)
+    os.Setenv("SPACE_DEACTIVATION_GRACE_DAYS", "0")
+    defer os.Unsetenv("SPACE_DEACTIVATION_GRACE_DAYS")
+
+    count, err := PurgeDeactivatedUsers()
+    assert.Nil(t, err, "should purge deactivated users")
+    assert.Equal(t, 0, count, "should not anonymize active users")
+    DeactivateUser(&user)
+    time.Sleep(time.Millisecond)
+    count, err = PurgeDeactivatedUsers()
+    assert.Nil(t, err, "should purge deactivated users")
+    assert.Equal(t, 1, count, "should anonymize users deactivated before the grace period")
+    anonymized := FindUserByID(user.ID)
+    assert.Equal(t, "Anonymized", anonymized.FirstName, "should replace personally identifiable information")
+    assert.Equal(t, uint(0), FindUserByAccountHolder(user.Email).ID, "should not find the user by their email")
+}
@@ -6,11 +6,28 @@ import (
 
     "github.com/earaujoassis/space/config"
     "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/memstore"
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/policy/password"
     "github.com/earaujoassis/space/web"
     "github.com/earaujoassis/space/api"
 )
 
 func Server() {
+    if _, err := config.ApplicationURL(); err != nil {
+        panic(fmt.Sprintf("Failed to start the application: %v\n", err))
+    }
+    if _, _, err := config.WebAuthnRelyingParty(); err != nil {
+        panic(fmt.Sprintf("Failed to start the application: %v\n", err))
+    }
+    if err := models.ValidatePasswordHasher(); err != nil {
+        panic(fmt.Sprintf("Failed to start the application: %v\n", err))
+    }
+    if err := password.Load(); err != nil {
+        panic(fmt.Sprintf("Failed to load the password policy: %v\n", err))
+    }
+    memstore.Start()
+    defer memstore.Close()
     datastore.Start()
     router := gin.Default()
     web.ExposeRoutes(router)
@@ -8,6 +8,7 @@ import (
 
     "github.com/earaujoassis/space/services"
     "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/security"
 )
 
 func CreateClient() {
@@ -27,14 +28,32 @@ func CreateClient() {
     fmt.Print("Client URI redirect: ")
     redirectURI, _ := reader.ReadString('\n')
     redirectURI = strings.Trim(redirectURI, "\n")
+    fmt.Print("Client type (public or confidential): ")
+    clientType, _ := reader.ReadString('\n')
+    clientType = strings.Trim(clientType, "\n")
+    if clientType != models.PublicClient {
+        clientType = models.ConfidentialClient
+    }
+    fmt.Print("Access token format (opaque or jwt): ")
+    accessTokenFormat, _ := reader.ReadString('\n')
+    accessTokenFormat = strings.Trim(accessTokenFormat, "\n")
+    if accessTokenFormat != models.JWTAccessToken {
+        accessTokenFormat = models.OpaqueAccessToken
+    }
 
-    clientSecret := models.GenerateRandomString(64)
+    clientSecret, err := security.GenerateToken(64)
+    if err != nil {
+        fmt.Printf("Client secret could not be generated: %v\n", err)
+        return
+    }
     client := services.CreateNewClient(clientName,
         clientDescription,
         clientSecret,
         clientScope,
         canonicalURI,
-        redirectURI)
+        redirectURI,
+        clientType,
+        accessTokenFormat)
     if client.ID == 0 {
         fmt.Println("There's a error and the client was not created")
     } else {
@@ -0,0 +1,48 @@
+package tasks
+
+import (
+    "fmt"
+
+    "github.com/earaujoassis/space/datastore"
+)
+
+// Failures are returned, so the command exits with a non-zero status (e.g. `db migrate && serve`)
+func MigrateDataStore() error {
+    migrations, err := datastore.Migrate()
+    for _, migration := range migrations {
+        fmt.Printf("Applied migration %d: %s\n", migration.Version, migration.Name)
+    }
+    if err != nil {
+        return fmt.Errorf("There's a error and the data store was not migrated: %v", err)
+    }
+    fmt.Printf("Migrations applied: %d\n", len(migrations))
+    return nil
+}
+
+func RollbackDataStore(steps int) error {
+    migrations, err := datastore.Rollback(steps)
+    for _, migration := range migrations {
+        fmt.Printf("Rolled back migration %d: %s\n", migration.Version, migration.Name)
+    }
+    if err != nil {
+        return fmt.Errorf("There's a error and the data store was not rolled back: %v", err)
+    }
+    fmt.Printf("Migrations rolled back: %d\n", len(migrations))
+    return nil
+}
+
+func DataStoreStatus() error {
+    states, err := datastore.Status()
+    if err != nil {
+        return fmt.Errorf("There's a error and the migrations status is unavailable: %v", err)
+    }
+    for _, state := range states {
+        if state.AppliedAt == nil {
+            fmt.Printf("%6d  %-8s  %-20s  %s\n", state.Version, "pending", "", state.Name)
+        } else {
+            fmt.Printf("%6d  %-8s  %-20s  %s\n", state.Version, "applied",
+                state.AppliedAt.Format("2006-01-02 15:04:05"), state.Name)
+        }
+    }
+    return nil
+}
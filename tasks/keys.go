@@ -0,0 +1,19 @@
+package tasks
+
+import (
+    "fmt"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/services"
+)
+
+func RotateKeys(algorithm string) {
+    datastore.Start()
+    key, err := services.RotateSigningKeys(algorithm)
+    if err != nil || key.ID == 0 {
+        fmt.Println("There's a error and the signing keys were not rotated:", err)
+    } else {
+        fmt.Println("The signing keys were rotated")
+        fmt.Println("Active key: ", key.Kid)
+    }
+}
@@ -0,0 +1,19 @@
+package tasks
+
+import (
+    "fmt"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/services"
+)
+
+func ReencryptSecrets() {
+    datastore.Start()
+    count, err := services.ReencryptSecrets()
+    if err != nil {
+        fmt.Println("There's a error and the secrets were not re-encrypted:", err)
+        fmt.Println("Keep every storage key until the secrets are re-encrypted without errors")
+        return
+    }
+    fmt.Printf("Secrets re-encrypted with the primary key: %d\n", count)
+}
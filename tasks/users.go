@@ -0,0 +1,17 @@
+package tasks
+
+import (
+    "fmt"
+
+    "github.com/earaujoassis/space/datastore"
+    "github.com/earaujoassis/space/services"
+)
+
+func PurgeUsers() {
+    datastore.Start()
+    count, err := services.PurgeDeactivatedUsers()
+    if err != nil {
+        fmt.Println("There's a error and the users were not purged:", err)
+    }
+    fmt.Printf("Deactivated users anonymized: %d\n", count)
+}
@@ -12,6 +12,9 @@ func BasicAuthEncode(key, secret string) string {
 
 func BasicAuthDecode(token string) (string, string) {
     bytes, _ := base64.StdEncoding.DecodeString(token)
-    values := strings.Split(string(bytes), ":")
+    values := strings.SplitN(string(bytes), ":", 2)
+    if len(values) < 2 {
+        return values[0], ""
+    }
     return values[0], values[1]
 }
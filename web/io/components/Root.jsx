@@ -33,7 +33,8 @@ export default class Root extends React.Component {
             return (<Success codeSecretImage={this.state.code_secret_image}
                 recoverSecret={this.state.recover_secret} />)
         } else {
-            return (<SignUp validationFailed={this.state.validationFailed} />)
+            return (<SignUp validationFailed={this.state.validationFailed}
+                passwordReasons={this.state.passwordReasons} />)
         }
     }
 
@@ -42,8 +43,10 @@ export default class Root extends React.Component {
             this.setState(UserStore.getState().payload || {})
         } else {
             let error = UserStore.getState().payload
-            if (error.user) {
-                this.setState({validationFailed: true})
+            if (error.reasons) {
+                this.setState({validationFailed: false, passwordReasons: error.reasons})
+            } else if (error.user) {
+                this.setState({validationFailed: true, passwordReasons: null})
             }
         }
     }
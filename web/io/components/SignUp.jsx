@@ -5,6 +5,13 @@ import UsersActions from '../actions/users'
 import Row from '../../core/components/Row.jsx'
 import Columns from '../../core/components/Columns.jsx'
 
+const passwordReasonMessages = {
+    too_short: 'Password is too short',
+    missing_character_classes: 'Password must mix lowercase and uppercase letters, digits or symbols',
+    personal_data: 'Password must not contain your name, username or email',
+    breached: 'Password was found in a data breach; please choose another one'
+}
+
 export default class SignUp extends React.Component {
     constructor() {
         super()
@@ -27,6 +34,11 @@ export default class SignUp extends React.Component {
                                     <p className="error-message">Validation failed</p>
                                 ) : null
                             }
+                            {
+                                (this.props.passwordReasons || []).map((reason) => (
+                                    <p className="error-message" key={reason}>{passwordReasonMessages[reason] || reason}</p>
+                                ))
+                            }
                             <Row>
                                 <Columns className="small-6">
                                     <input type="text" name="first_name" placeholder="First Name" />
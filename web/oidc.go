@@ -0,0 +1,94 @@
+package web
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/earaujoassis/space/config"
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/oauth"
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/utils"
+)
+
+func issuerURI(request *http.Request) string {
+    if issuer := config.GetConfig("SPACE_OIDC_ISSUER"); issuer != "" {
+        return strings.TrimRight(issuer, "/")
+    }
+    scheme := request.Header.Get("X-Forwarded-Proto")
+    if scheme == "" && request.TLS == nil {
+        scheme = "http"
+    } else if scheme == "" {
+        scheme = "https"
+    }
+    return fmt.Sprintf("%s://%s", scheme, request.Host)
+}
+
+// OpenID Connect Core 1.0, section 5.3
+func userinfoHandler(c *gin.Context) {
+    authorizationBearer := strings.Replace(c.Request.Header.Get("Authorization"), "Bearer ", "", 1)
+
+    if !security.ValidToken(authorizationBearer) && !security.ValidJWT(authorizationBearer) {
+        c.JSON(http.StatusBadRequest, utils.H{
+            "error": oauth.InvalidRequest,
+        })
+        return
+    }
+
+    session := oauth.AccessAuthentication(authorizationBearer)
+    if session.UUID == "" || !session.HasUser() || !models.ScopeIncludes(session.Scopes, models.OpenIDScope) {
+        c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"%s\", error=\"invalid_token\"", c.Request.RequestURI))
+        c.JSON(http.StatusUnauthorized, utils.H{
+            "error": oauth.AccessDenied,
+        })
+        return
+    }
+
+    user := services.FindUserByPublicId(session.User.PublicId)
+    claims := utils.H{
+        "sub": user.PublicId,
+    }
+    if services.SessionGrantsReadAbility(session) {
+        claims["preferred_username"] = user.Username
+        claims["name"] = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+        claims["given_name"] = user.FirstName
+        claims["family_name"] = user.LastName
+        claims["email"] = user.Email
+        claims["zoneinfo"] = user.TimezoneIdentifier
+        claims["locale"] = user.Language.IsoCode
+    }
+    c.JSON(http.StatusOK, claims)
+}
+
+// OpenID Connect Discovery 1.0, section 3
+func discoveryHandler(c *gin.Context) {
+    issuer := issuerURI(c.Request)
+    c.JSON(http.StatusOK, utils.H{
+        "issuer": issuer,
+        "authorization_endpoint": fmt.Sprintf("%s/authorize", issuer),
+        "token_endpoint": fmt.Sprintf("%s/token", issuer),
+        "userinfo_endpoint": fmt.Sprintf("%s/userinfo", issuer),
+        "jwks_uri": fmt.Sprintf("%s/.well-known/jwks.json", issuer),
+        "introspection_endpoint": fmt.Sprintf("%s/oauth/introspect", issuer),
+        "revocation_endpoint": fmt.Sprintf("%s/oauth/revoke", issuer),
+        "scopes_supported": []string{models.OpenIDScope, models.PublicScope, models.ReadScope, models.ReadWriteScope},
+        "response_types_supported": []string{oauth.Code},
+        "grant_types_supported": []string{oauth.AuthorizationCode, oauth.RefreshToken, oauth.Password, oauth.ClientCredentials},
+        "subject_types_supported": []string{"public"},
+        "id_token_signing_alg_values_supported": oauth.SigningAlgorithms(),
+        "token_endpoint_auth_methods_supported": []string{"client_secret_basic", "none"},
+        "code_challenge_methods_supported": []string{oauth.PlainCodeChallenge, oauth.S256CodeChallenge},
+        "claims_supported": []string{"sub", "iss", "aud", "exp", "iat", "nonce", "preferred_username",
+            "name", "given_name", "family_name", "email", "zoneinfo", "locale"},
+    })
+}
+
+func jwksHandler(c *gin.Context) {
+    c.JSON(http.StatusOK, utils.H{
+        "keys": oauth.PublicKeys(),
+    })
+}
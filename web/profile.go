@@ -0,0 +1,91 @@
+package web
+
+import (
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/earaujoassis/space/models"
+    "github.com/earaujoassis/space/policy"
+    "github.com/earaujoassis/space/services"
+    "github.com/earaujoassis/space/services/logger"
+    "github.com/earaujoassis/space/security"
+    "github.com/earaujoassis/space/utils"
+)
+
+// Users are activated once they follow the signed link sent to their email
+func userConfirmationHandler(c *gin.Context) {
+    var token string = c.Query("token")
+
+    uuid, err := models.UserUUIDFromConfirmationToken(token)
+    if err != nil || !security.ValidUUID(uuid) {
+        c.HTML(http.StatusBadRequest, "error", utils.H{
+            "AssetsEndpoint": spaceCDN,
+        })
+        return
+    }
+
+    user := services.FindUserByUUID(uuid)
+    if user.ID == 0 || user.DeactivatedAt != nil {
+        c.HTML(http.StatusBadRequest, "error", utils.H{
+            "AssetsEndpoint": spaceCDN,
+        })
+        return
+    }
+    if !user.Confirmed() {
+        user.Confirm()
+        if err := services.SaveUser(&user); err != nil {
+            c.HTML(http.StatusBadRequest, "error", utils.H{
+                "AssetsEndpoint": spaceCDN,
+            })
+            return
+        }
+        policy.RegisterSuccessfulConfirmation(user.UUID)
+        go logger.LogAction("user.confirmed", utils.H{
+            "Email": user.Email,
+            "FirstName": user.FirstName,
+        })
+    }
+
+    c.Redirect(http.StatusFound, "/signin")
+}
+
+// The email of an account is changed once the link sent to the new email is followed
+func emailConfirmationHandler(c *gin.Context) {
+    var token string = c.Query("token")
+
+    if !security.ValidToken(token) {
+        c.HTML(http.StatusBadRequest, "error", utils.H{
+            "AssetsEndpoint": spaceCDN,
+        })
+        return
+    }
+
+    change := services.FindEmailChangeByToken(token)
+    user := services.FindUserByID(change.UserID)
+    // Links issued before the account was deactivated can't change its email
+    if change.UUID == "" || user.ID == 0 || !user.Active || user.DeactivatedAt != nil ||
+            services.FindUserByAccountHolder(change.Email).ID != 0 {
+        c.HTML(http.StatusBadRequest, "error", utils.H{
+            "AssetsEndpoint": spaceCDN,
+        })
+        return
+    }
+
+    previousEmail := user.Email
+    user.Email = change.Email
+    if err := services.SaveUser(&user); err != nil {
+        c.HTML(http.StatusBadRequest, "error", utils.H{
+            "AssetsEndpoint": spaceCDN,
+        })
+        return
+    }
+    change.Delete()
+    go logger.LogAction("user.email.changed", utils.H{
+        "Email": previousEmail,
+        "FirstName": user.FirstName,
+        "NewEmail": user.Email,
+    })
+
+    c.Redirect(http.StatusFound, "/profile")
+}
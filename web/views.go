@@ -50,6 +50,7 @@ func ExposeRoutes(router *gin.Engine) {
     {
         views.GET("/", jupiterHandler)
         views.GET("/profile", jupiterHandler)
+        views.GET("/profile/email/confirm", emailConfirmationHandler)
 
         views.GET("/signup", func(c *gin.Context) {
             c.HTML(http.StatusOK, "satellite", utils.H{
@@ -64,6 +65,8 @@ func ExposeRoutes(router *gin.Engine) {
             })
         })
 
+        views.GET("/signup/confirm", userConfirmationHandler)
+
         views.GET("/signin", func(c *gin.Context) {
             c.HTML(http.StatusOK, "satellite", utils.H{
                 "AssetsEndpoint": spaceCDN,
@@ -72,6 +75,17 @@ func ExposeRoutes(router *gin.Engine) {
             })
         })
 
+        views.GET("/password/reset", func(c *gin.Context) {
+            c.HTML(http.StatusOK, "satellite", utils.H{
+                "AssetsEndpoint": spaceCDN,
+                "Title": " - Reset password",
+                "Satellite": "ganymede",
+                "Data": utils.H{
+                    "reset_token": c.Query("token"),
+                },
+            })
+        })
+
         views.GET("/signout", func(c *gin.Context) {
             session := sessions.Default(c)
 
@@ -140,11 +154,23 @@ func ExposeRoutes(router *gin.Engine) {
             })
         })
 
+        views.POST("/oauth/introspect", introspectHandler)
+        views.POST("/oauth/revoke", revokeHandler)
+
+        views.GET("/userinfo", userinfoHandler)
+        views.POST("/userinfo", userinfoHandler)
+        views.GET("/.well-known/openid-configuration", discoveryHandler)
+        views.GET("/.well-known/jwks.json", jwksHandler)
+
         views.POST("/token", func(c *gin.Context) {
             var grantType string = c.PostForm("grant_type")
+            var client models.Client
 
             authorizationBasic := strings.Replace(c.Request.Header.Get("Authorization"), "Basic ", "", 1)
-            client := oauth.ClientAuthentication(authorizationBasic)
+            // Public clients are issued refresh tokens with PKCE (RFC 7636), so they can refresh them as well
+            publicAllowed := (grantType == oauth.AuthorizationCode && c.PostForm("code_verifier") != "") ||
+                grantType == oauth.RefreshToken
+            client = oauth.RequestingClient(authorizationBasic, c.PostForm("client_id"), publicAllowed)
             if client.ID == 0 {
                 c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\"", c.Request.RequestURI))
                 c.JSON(http.StatusUnauthorized, utils.H{
@@ -160,6 +186,8 @@ func ExposeRoutes(router *gin.Engine) {
                     "grant_type": grantType,
                     "code": c.PostForm("code"),
                     "redirect_uri": c.PostForm("redirect_uri"),
+                    "code_verifier": c.PostForm("code_verifier"),
+                    "issuer": issuerURI(c.Request),
                     "client": client,
                 })
                 if err != nil {
@@ -168,14 +196,18 @@ func ExposeRoutes(router *gin.Engine) {
                     })
                     return
                 } else {
-                    c.JSON(http.StatusOK, utils.H{
+                    response := utils.H{
                         "user_id": result["user_id"],
                         "access_token": result["access_token"],
                         "token_type": result["token_type"],
                         "expires_in": result["expires_in"],
                         "refresh_token": result["refresh_token"],
                         "scope": result["scope"],
-                    })
+                    }
+                    if result["id_token"] != nil {
+                        response["id_token"] = result["id_token"]
+                    }
+                    c.JSON(http.StatusOK, response)
                     return
                 }
                 return
@@ -184,6 +216,7 @@ func ExposeRoutes(router *gin.Engine) {
                 result, err := oauth.RefreshTokenRequest(utils.H{
                     "grant_type": grantType,
                     "refresh_token": c.PostForm("refresh_token"),
+                    "issuer": issuerURI(c.Request),
                     "scope": c.PostForm("scope"),
                     "client": client,
                 })
@@ -205,11 +238,59 @@ func ExposeRoutes(router *gin.Engine) {
                 }
                 return
             // Resource Owner Password Credentials Grant
+            case oauth.Password:
+                result, err := oauth.PasswordCredentialsRequest(utils.H{
+                    "grant_type": grantType,
+                    "username": c.PostForm("username"),
+                    "password": c.PostForm("password"),
+                    "passcode": c.PostForm("passcode"),
+                    "issuer": issuerURI(c.Request),
+                    "scope": c.PostForm("scope"),
+                    "ip": c.Request.RemoteAddr,
+                    "userAgent": c.Request.UserAgent(),
+                    "client": client,
+                })
+                if err != nil {
+                    c.JSON(http.StatusMethodNotAllowed, utils.H{
+                        "error": result["error"],
+                    })
+                    return
+                } else {
+                    c.JSON(http.StatusOK, utils.H{
+                        "user_id": result["user_id"],
+                        "access_token": result["access_token"],
+                        "token_type": result["token_type"],
+                        "expires_in": result["expires_in"],
+                        "refresh_token": result["refresh_token"],
+                        "scope": result["scope"],
+                    })
+                    return
+                }
+                return
             // Client Credentials Grant
-            case oauth.Password, oauth.ClientCredentials:
-                c.JSON(http.StatusMethodNotAllowed, utils.H{
-                    "error": oauth.UnsupportedGrantType,
+            case oauth.ClientCredentials:
+                result, err := oauth.ClientCredentialsRequest(utils.H{
+                    "grant_type": grantType,
+                    "issuer": issuerURI(c.Request),
+                    "scope": c.PostForm("scope"),
+                    "ip": c.Request.RemoteAddr,
+                    "userAgent": c.Request.UserAgent(),
+                    "client": client,
                 })
+                if err != nil {
+                    c.JSON(http.StatusMethodNotAllowed, utils.H{
+                        "error": result["error"],
+                    })
+                    return
+                } else {
+                    c.JSON(http.StatusOK, utils.H{
+                        "access_token": result["access_token"],
+                        "token_type": result["token_type"],
+                        "expires_in": result["expires_in"],
+                        "scope": result["scope"],
+                    })
+                    return
+                }
                 return
             default:
                 c.JSON(http.StatusBadRequest, utils.H{
@@ -230,6 +311,12 @@ func jupiterHandler(c *gin.Context) {
     }
     client := services.FindOrCreateClient("Jupiter")
     user := services.FindUserByPublicId(userPublicId.(string))
+    if user.ID == 0 || !user.Active {
+        session.Delete("userPublicId")
+        session.Save()
+        c.Redirect(http.StatusFound, "/signin")
+        return
+    }
     actionToken := services.CreateAction(user, client,
         c.Request.RemoteAddr,
         c.Request.UserAgent(),
@@ -252,6 +339,10 @@ func authorizeHandler(c *gin.Context) {
     var redirectURI string
     var scope string
     var state string
+    var codeChallenge string
+    var codeChallengeMethod string
+    var nonce string
+    var openID bool
 
     session := sessions.Default(c)
     userPublicId := session.Get("userPublicId")
@@ -262,7 +353,7 @@ func authorizeHandler(c *gin.Context) {
         return
     }
     user := services.FindUserByPublicId(userPublicId.(string))
-    if user.ID == 0 {
+    if user.ID == 0 || !user.Active {
         session.Delete("userPublicId")
         session.Save()
         location = fmt.Sprintf("/signin?_=%s", nextPath)
@@ -273,8 +364,11 @@ func authorizeHandler(c *gin.Context) {
     responseType = c.Query("response_type")
     clientId = c.Query("client_id")
     redirectURI = c.Query("redirect_uri")
-    scope = c.Query("scope")
+    scope, openID = oauth.ParseScope(c.Query("scope"))
     state = c.Query("state")
+    nonce = c.Query("nonce")
+    codeChallenge = c.Query("code_challenge")
+    codeChallengeMethod = c.Query("code_challenge_method")
 
     if redirectURI == "" {
         redirectURI = "/error"
@@ -289,10 +383,6 @@ func authorizeHandler(c *gin.Context) {
         return
     }
 
-    if scope != models.PublicScope && scope != models.ReadScope && scope != models.ReadWriteScope {
-        scope = "public"
-    }
-
     switch responseType {
     // Authorization Code Grant
     case oauth.Code:
@@ -326,13 +416,17 @@ func authorizeHandler(c *gin.Context) {
                 "redirect_uri": redirectURI,
                 "scope": scope,
                 "state": state,
+                "code_challenge": codeChallenge,
+                "code_challenge_method": codeChallengeMethod,
+                "openid": openID,
+                "nonce": nonce,
             })
             if err != nil {
                 location = fmt.Sprintf(errorURI, redirectURI, result["error"], result["state"])
                 c.Redirect(http.StatusFound, location)
             } else {
                 location = fmt.Sprintf("%s?code=%s&scope=%s&state=%s",
-                    redirectURI, result["code"], result["scope"], result["state"])
+                    redirectURI, result["code"], url.QueryEscape(result["scope"].(string)), result["state"])
                 c.Redirect(http.StatusFound, location)
             }
         } else {
@@ -351,3 +445,55 @@ func authorizeHandler(c *gin.Context) {
         return
     }
 }
+
+// Token introspection (RFC 7662); protected by client authentication
+func introspectHandler(c *gin.Context) {
+    authorizationBasic := strings.Replace(c.Request.Header.Get("Authorization"), "Basic ", "", 1)
+    // Public clients revoke the tokens they were issued with their client_id only
+    client := oauth.RequestingClient(authorizationBasic, c.PostForm("client_id"), true)
+    if client.ID == 0 {
+        c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\"", c.Request.RequestURI))
+        c.JSON(http.StatusUnauthorized, utils.H{
+            "error": oauth.AccessDenied,
+        })
+        return
+    }
+
+    result, err := oauth.IntrospectionRequest(utils.H{
+        "token": c.PostForm("token"),
+        "token_type_hint": c.PostForm("token_type_hint"),
+    })
+    if err != nil {
+        c.JSON(http.StatusBadRequest, utils.H{
+            "error": result["error"],
+        })
+        return
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+// Token revocation (RFC 7009); protected by client authentication
+func revokeHandler(c *gin.Context) {
+    authorizationBasic := strings.Replace(c.Request.Header.Get("Authorization"), "Basic ", "", 1)
+    client := oauth.ClientAuthentication(authorizationBasic)
+    if client.ID == 0 {
+        c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=\"%s\"", c.Request.RequestURI))
+        c.JSON(http.StatusUnauthorized, utils.H{
+            "error": oauth.AccessDenied,
+        })
+        return
+    }
+
+    result, err := oauth.RevocationRequest(utils.H{
+        "token": c.PostForm("token"),
+        "token_type_hint": c.PostForm("token_type_hint"),
+        "client": client,
+    })
+    if err != nil {
+        c.JSON(http.StatusBadRequest, utils.H{
+            "error": result["error"],
+        })
+        return
+    }
+    c.Status(http.StatusOK)
+}